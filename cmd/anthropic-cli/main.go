@@ -5,31 +5,34 @@ import (
     "flag"
     "fmt"
     "os"
-    "strings"
 
-    "github.com/Aanthord/anthropic-go/pkg/api"
-    "github.com/Aanthord/anthropic-go/pkg/models"
+    "github.com/Aanthord/go-anthropic/pkg/api"
+    "github.com/Aanthord/go-anthropic/pkg/models"
 )
 
 func main() {
-    apiKey := flag.String("api-key", "", "Anthropic API key")
-    model := flag.String("model", "", "Model to use for completion/chat")
+    apiKey := flag.String("api-key", "", "Anthropic API key (defaults to $ANTHROPIC_API_KEY)")
+    model := flag.String("model", "", "Model to use for completion/chat (defaults to $ANTHROPIC_MODEL)")
     prompt := flag.String("prompt", "", "Prompt to send for completion/chat")
     stream := flag.Bool("stream", false, "Stream the completion/chat response")
     chat := flag.Bool("chat", false, "Use chat completion instead of text completion")
     flag.Parse()
 
-    if *apiKey == "" {
-        fmt.Println("Please provide an API key with -api-key")
-        os.Exit(1)
-    }
-
     if *prompt == "" {
         fmt.Println("Please provide a prompt with -prompt")
         os.Exit(1)
     }
 
-    client := api.NewClient(*apiKey)
+    var opts []api.ClientOption
+    if *apiKey != "" {
+        opts = append(opts, api.WithAPIKey(*apiKey))
+    }
+    client, err := api.NewClientFromEnv(opts...)
+    if err != nil {
+        fmt.Printf("Error: %v\n", err)
+        fmt.Println("Provide an API key with -api-key or the ANTHROPIC_API_KEY environment variable")
+        os.Exit(1)
+    }
 
     ctx := context.Background()
 
@@ -50,7 +53,7 @@ func main() {
             for {
                 select {
                 case resp := <-respStream:
-                    fmt.Printf("Assistant: %s", resp.Choices[0].Message.Content)
+                    fmt.Printf("Assistant: %s", resp.Text())
                 case err := <-errStream:
                     fmt.Printf("Error: %v\n", err)
                     return
@@ -73,7 +76,7 @@ func main() {
                 os.Exit(1)
             }
 
-            fmt.Printf("Assistant: %s\n", resp.Choices[0].Message.Content)
+            fmt.Printf("Assistant: %s\n", resp.Text())
         }
     } else {
         if *stream {
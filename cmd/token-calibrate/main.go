@@ -0,0 +1,150 @@
+// Command token-calibrate fits the factors of the offline token estimator
+// to the input token usage recorded in response logs.
+//
+// Logs are recorder cassettes, or JSON Lines files with one
+// {"request": ..., "response": ...} object per line, where response is the
+// response body, or a string holding the event stream of a streamed one.
+// The fitted factors are printed as JSON, ready for tokens.LoadFactors:
+//
+//	token-calibrate -o factors.json testdata/*.json
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Aanthord/go-anthropic/pkg/recorder"
+	"github.com/Aanthord/go-anthropic/pkg/tokens"
+)
+
+func main() {
+	prior := flag.String("prior", "", "JSON file with the factors to start from (defaults to the built-in factors)")
+	output := flag.String("o", "", "Write the fitted factors to this file instead of stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] log...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*prior, *output, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(priorPath, output string, paths []string) error {
+	prior := tokens.DefaultFactors
+	if priorPath != "" {
+		var err error
+		if prior, err = tokens.LoadFactors(priorPath); err != nil {
+			return err
+		}
+	}
+
+	var samples []tokens.Sample
+	for _, path := range paths {
+		s, err := readSamples(path)
+		if err != nil {
+			return err
+		}
+		samples = append(samples, s...)
+	}
+
+	fitted, err := tokens.Fit(samples, prior)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d samples, mean error %.1f%% before and %.1f%% after calibration\n",
+		len(samples), 100*tokens.MeanError(samples, prior), 100*tokens.MeanError(samples, fitted))
+
+	data, err := json.MarshalIndent(fitted, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(output, data, 0o644)
+}
+
+// readSamples reads the samples of a cassette or JSON Lines log. Entries
+// that are not successful message requests with usage are skipped.
+func readSamples(path string) ([]tokens.Sample, error) {
+	if filepath.Ext(path) == ".jsonl" {
+		return readLines(path)
+	}
+	cassette, err := recorder.LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	var samples []tokens.Sample
+	for _, interaction := range cassette.Interactions {
+		if interaction.Response.StatusCode != 200 || !isMessagesURL(interaction.Request.URL) {
+			continue
+		}
+		sample, err := tokens.ParseSample([]byte(interaction.Request.Body), []byte(interaction.Response.Body()))
+		if err == nil {
+			samples = append(samples, sample)
+		} else if !errors.Is(err, tokens.ErrNoUsage) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return samples, nil
+}
+
+// isMessagesURL reports whether rawURL addresses the messages endpoint,
+// ignoring any query string.
+func isMessagesURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && strings.HasSuffix(u.Path, "/v1/messages")
+}
+
+func readLines(path string) ([]tokens.Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var samples []tokens.Sample
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry struct {
+			Request  json.RawMessage `json:"request"`
+			Response json.RawMessage `json:"response"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		// A streamed response body is logged as a JSON string.
+		response := []byte(entry.Response)
+		var body string
+		if json.Unmarshal(entry.Response, &body) == nil {
+			response = []byte(body)
+		}
+		sample, err := tokens.ParseSample(entry.Request, response)
+		if err == nil {
+			samples = append(samples, sample)
+		} else if !errors.Is(err, tokens.ErrNoUsage) {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	return samples, scanner.Err()
+}
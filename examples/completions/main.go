@@ -4,8 +4,8 @@ import (
     "context"
     "fmt"
 
-    "github.com/Aanthord/anthropic-go/pkg/api"
-    "github.com/Aanthord/anthropic-go/pkg/models"
+    "github.com/Aanthord/go-anthropic/pkg/api"
+    "github.com/Aanthord/go-anthropic/pkg/models"
 )
 
 func main() {
@@ -4,8 +4,8 @@ import (
     "context"  
     "fmt"
 
-    "github.com/Aanthord/anthropic-go/pkg/api"
-    "github.com/Aanthord/anthropic-go/pkg/models"  
+    "github.com/Aanthord/go-anthropic/pkg/api"
+    "github.com/Aanthord/go-anthropic/pkg/models"  
 )
 
 func main() {
@@ -32,7 +32,7 @@ func main() {
         panic(err)  
     }
 
-    fmt.Println(resp.Choices[0].Message.Content)
+    fmt.Println(resp.Text())
 
     streamReq := &models.MessageRequest{
         Messages: []models.Message{
@@ -49,7 +49,7 @@ func main() {
     for {
         select {
         case resp := <-stream:
-            fmt.Print(resp.Choices[0].Message.Content)  
+            fmt.Print(resp.Text())  
         case err := <-errStream:
             fmt.Printf("Error: %v\n", err)  
             return
@@ -1,3 +1,17 @@
 module github.com/Aanthord/go-anthropic
 
 go 1.22.0
+
+require (
+	go.etcd.io/bbolt v1.3.10
+	go.opentelemetry.io/otel v1.26.0
+	go.opentelemetry.io/otel/sdk v1.26.0
+	go.opentelemetry.io/otel/trace v1.26.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.26.0 // indirect
+	golang.org/x/sys v0.19.0 // indirect
+)
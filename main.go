@@ -1 +1,5 @@
-package main\n\nfunc main() {\n\t// Your code here\n}
+package main
+
+func main() {
+	// Your code here
+}
@@ -0,0 +1,227 @@
+// pkg/anthropictest/fake.go
+package anthropictest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/internal/errors"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
+)
+
+// Call is a call received by a Fake.
+type Call struct {
+	// Operation is the name of the method, e.g. "CreateMessage".
+	Operation string
+	Request   *models.MessageRequest
+}
+
+// Fake is a scripted api.MessageClient that answers without any HTTP. It
+// serves queued responses first and then falls back to Respond. Request
+// options are accepted and ignored.
+type Fake struct {
+	// Respond computes a response when the queue is empty. By default the
+	// fake echoes the last user message.
+	Respond func(req *models.MessageRequest) Response
+	// Tokens computes the CountTokens result. By default it estimates
+	// four characters per token.
+	Tokens func(req *models.MessageRequest) int
+	// ChunkSize is the number of runes per streamed text chunk. Zero streams
+	// each choice in one chunk.
+	ChunkSize int
+
+	mu    sync.Mutex
+	queue []Response
+	calls []Call
+	ids   int
+}
+
+var _ api.MessageClient = (*Fake)(nil)
+
+// NewFake creates a Fake that echoes requests until responses are queued.
+func NewFake() *Fake {
+	return &Fake{Respond: Echo, Tokens: estimateTokens}
+}
+
+// Enqueue adds scripted responses, served in order before Respond is used.
+func (f *Fake) Enqueue(responses ...Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queue = append(f.queue, responses...)
+}
+
+// EnqueueText queues an assistant turn with text that ends the turn.
+func (f *Fake) EnqueueText(text string) {
+	f.Enqueue(Response{Message: TextMessage(text)})
+}
+
+// EnqueueToolUse queues an assistant turn that calls a tool with input.
+func (f *Fake) EnqueueToolUse(id, name string, input interface{}) {
+	f.Enqueue(Response{Message: ToolUseMessage(id, name, input)})
+}
+
+// EnqueueError queues an API error.
+func (f *Fake) EnqueueError(err *Error) {
+	f.Enqueue(Response{Error: err})
+}
+
+// Calls returns every call received so far.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// LastRequest returns the request of the most recent call, or nil.
+func (f *Fake) LastRequest() *models.MessageRequest {
+	calls := f.Calls()
+	if len(calls) == 0 {
+		return nil
+	}
+	return calls[len(calls)-1].Request
+}
+
+// AssertCallCount fails t unless exactly n calls were made to operation.
+func (f *Fake) AssertCallCount(t testing.TB, operation string, n int) {
+	t.Helper()
+	count := 0
+	for _, call := range f.Calls() {
+		if call.Operation == operation {
+			count++
+		}
+	}
+	if count != n {
+		t.Errorf("expected %d %s calls, got %d", n, operation, count)
+	}
+}
+
+// AssertQueueEmpty fails t if scripted responses were never served.
+func (f *Fake) AssertQueueEmpty(t testing.TB) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queue) > 0 {
+		t.Errorf("expected all scripted responses to be served, %d left", len(f.queue))
+	}
+}
+
+// CreateMessage implements api.MessageClient.
+func (f *Fake) CreateMessage(ctx context.Context, req *models.MessageRequest, opts ...api.RequestOption) (*models.MessageResponse, error) {
+	resp, err := f.next(ctx, "CreateMessage", req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create message: %w", err)
+	}
+	return f.prepare(req, resp.Message), nil
+}
+
+// StreamMessages implements api.MessageClient. The response is split into
+// chunks as the server would stream it. A scripted StreamError is delivered
+// after StreamErrorAfter chunks.
+func (f *Fake) StreamMessages(ctx context.Context, req *models.MessageRequest, opts ...api.RequestOption) (<-chan models.MessageResponse, <-chan error) {
+	req.Stream = true
+	errCh := make(chan error, 1)
+	resp, err := f.next(ctx, "StreamMessages", req)
+	if err != nil {
+		outputCh := make(chan models.MessageResponse)
+		close(outputCh)
+		errCh <- fmt.Errorf("failed to stream messages: %w", err)
+		close(errCh)
+		return outputCh, errCh
+	}
+
+	chunks := streams.MessageChunks(f.prepare(req, resp.Message), f.ChunkSize)
+	if resp.StreamError != nil && resp.StreamErrorAfter < len(chunks) {
+		chunks = chunks[:resp.StreamErrorAfter]
+		errCh <- resp.StreamError.apiError()
+	}
+	close(errCh)
+	outputCh := make(chan models.MessageResponse, len(chunks))
+	for _, chunk := range chunks {
+		outputCh <- chunk
+	}
+	close(outputCh)
+	return outputCh, errCh
+}
+
+// CountTokens implements api.MessageClient.
+func (f *Fake) CountTokens(ctx context.Context, req *models.MessageRequest, opts ...api.RequestOption) (*models.CountTokensResponse, error) {
+	f.record("CountTokens", req)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return &models.CountTokensResponse{InputTokens: f.Tokens(req)}, nil
+}
+
+func (f *Fake) record(operation string, req *models.MessageRequest) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Operation: operation, Request: req})
+}
+
+// next records the call, pops the next scripted response or computes one and
+// waits out its latency.
+func (f *Fake) next(ctx context.Context, operation string, req *models.MessageRequest) (Response, error) {
+	f.record(operation, req)
+
+	f.mu.Lock()
+	var resp Response
+	if len(f.queue) > 0 {
+		resp = f.queue[0]
+		f.queue = f.queue[1:]
+		f.mu.Unlock()
+	} else {
+		respond := f.Respond
+		f.mu.Unlock()
+		resp = respond(req)
+	}
+
+	if resp.Latency > 0 {
+		select {
+		case <-time.After(resp.Latency):
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return resp, err
+	}
+	if resp.Error != nil {
+		return resp, resp.Error.apiError()
+	}
+	return resp, nil
+}
+
+// prepare fills in the ID, model and usage of a scripted message.
+func (f *Fake) prepare(req *models.MessageRequest, message *models.MessageResponse) *models.MessageResponse {
+	if message == nil {
+		message = TextMessage("")
+	}
+	prepared := *message
+	if prepared.ID == "" {
+		f.mu.Lock()
+		f.ids++
+		prepared.ID = fmt.Sprintf("msg_fake_%03d", f.ids)
+		f.mu.Unlock()
+	}
+	if prepared.Object == "" {
+		prepared.Object = "message"
+	}
+	if req.Model != "" {
+		prepared.Model = req.Model
+	}
+	if prepared.Usage.PromptTokens == 0 {
+		prepared.Usage.PromptTokens = f.Tokens(req)
+	}
+	prepared.Usage.TotalTokens = prepared.Usage.PromptTokens + prepared.Usage.CompletionTokens
+	return &prepared
+}
+
+// apiError converts e to the error the client returns for it.
+func (e *Error) apiError() error {
+	return errors.APIError{StatusCode: e.StatusCode, Message: string(e.body())}
+}
@@ -0,0 +1,555 @@
+// pkg/anthropictest/server.go
+package anthropictest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
+)
+
+// Error is an API error returned by the fake server.
+type Error struct {
+	StatusCode int
+	Type       string
+	Message    string
+	// RetryAfter is sent as the retry-after header when non-zero.
+	RetryAfter time.Duration
+}
+
+// RateLimitError returns a 429 rate_limit_error.
+func RateLimitError() *Error {
+	return &Error{StatusCode: http.StatusTooManyRequests, Type: "rate_limit_error", Message: "Number of requests has exceeded your rate limit", RetryAfter: time.Second}
+}
+
+// OverloadedError returns a 529 overloaded_error.
+func OverloadedError() *Error {
+	return &Error{StatusCode: 529, Type: "overloaded_error", Message: "Overloaded"}
+}
+
+// InvalidRequestError returns a 400 invalid_request_error with message.
+func InvalidRequestError(message string) *Error {
+	return &Error{StatusCode: http.StatusBadRequest, Type: "invalid_request_error", Message: message}
+}
+
+func (e *Error) body() []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"type":  "error",
+		"error": map[string]string{"type": e.Type, "message": e.Message},
+	})
+	return body
+}
+
+// Response is a scripted answer to a message request, served by a Server or a Fake.
+type Response struct {
+	// Message is returned as JSON or streamed as chunks.
+	Message *models.MessageResponse
+	// Error is returned instead of Message.
+	Error *Error
+	// StreamError is sent as an error event after StreamErrorAfter chunks
+	// of a streamed Message, simulating a failure mid-stream.
+	StreamError      *Error
+	StreamErrorAfter int
+	// Latency delays the response in addition to the server's Latency.
+	Latency time.Duration
+	// ChunkDelay is the pause before each streamed chunk.
+	ChunkDelay time.Duration
+}
+
+// Request is a request received by the fake server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+	// Message is the decoded body of /v1/messages and count_tokens requests.
+	Message *models.MessageRequest
+}
+
+// Server is an in-process fake of the Anthropic API built on httptest. It
+// serves /v1/messages (JSON and streaming), /v1/messages/count_tokens,
+// /v1/models and message batches.
+type Server struct {
+	*httptest.Server
+
+	// Latency delays every response.
+	Latency time.Duration
+	// PingInterval sends ping events during stream pauses. Zero disables them.
+	PingInterval time.Duration
+	// ChunkSize is the number of runes per streamed text chunk. Zero streams
+	// each choice in one chunk.
+	ChunkSize int
+	// Respond computes a response when the scripted queue is empty. By
+	// default the server echoes the last user message.
+	Respond func(req *models.MessageRequest) Response
+	// Tokens computes the count_tokens result. By default it estimates
+	// four characters per token.
+	Tokens func(req *models.MessageRequest) int
+	// Models is returned from /v1/models.
+	Models []models.Model
+	// Header is added to every response, e.g. anthropic-ratelimit-* headers.
+	Header http.Header
+
+	mu        sync.Mutex
+	queue     []Response
+	requests  []Request
+	batches   map[string]*models.Batch
+	results   map[string][]models.BatchResult
+	idCounter int
+}
+
+// NewServer starts a fake server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		Respond: Echo,
+		Tokens:  estimateTokens,
+		Models:  []models.Model{{ID: "claude-test", Object: "model"}},
+		Header:  make(http.Header),
+		batches: make(map[string]*models.Batch),
+		results: make(map[string][]models.BatchResult),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/messages", s.handleMessages)
+	mux.HandleFunc("/v1/messages/count_tokens", s.handleCountTokens)
+	mux.HandleFunc("/v1/messages/batches", s.handleCreateBatch)
+	mux.HandleFunc("/v1/messages/batches/", s.handleBatch)
+	mux.HandleFunc("/v1/models", s.handleModels)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		for key, values := range s.Header {
+			w.Header()[key] = append([]string(nil), values...)
+		}
+		s.mu.Unlock()
+		w.Header().Set("request-id", s.newID("req"))
+		mux.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// Enqueue adds scripted responses, served in order before Respond is used.
+func (s *Server) Enqueue(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, responses...)
+}
+
+// EnqueueText queues an assistant turn with text that ends the turn.
+func (s *Server) EnqueueText(text string) {
+	s.Enqueue(Response{Message: TextMessage(text)})
+}
+
+// EnqueueToolUse queues an assistant turn that calls a tool with input.
+func (s *Server) EnqueueToolUse(id, name string, input interface{}) {
+	s.Enqueue(Response{Message: ToolUseMessage(id, name, input)})
+}
+
+// EnqueueError queues an API error.
+func (s *Server) EnqueueError(err *Error) {
+	s.Enqueue(Response{Error: err})
+}
+
+// TextMessage builds a response whose single choice is text with stop reason end_turn.
+func TextMessage(text string) *models.MessageResponse {
+	return &models.MessageResponse{
+		Model: "claude-test",
+		Choices: []models.MessageChoice{{
+			Message:      models.Message{Role: models.AssistantRole, Content: text},
+			FinishReason: "end_turn",
+		}},
+		Usage: models.MessageUsage{CompletionTokens: estimateText(text)},
+	}
+}
+
+// ToolUseMessage builds a response that calls a tool, with stop reason tool_use.
+func ToolUseMessage(id, name string, input interface{}) *models.MessageResponse {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		panic(fmt.Sprintf("anthropictest: invalid tool input: %v", err))
+	}
+	return &models.MessageResponse{
+		Model: "claude-test",
+		Choices: []models.MessageChoice{{
+			Message: models.Message{
+				Role:   models.AssistantRole,
+				Blocks: []models.ContentBlock{{Type: models.ToolUseBlock, ID: id, Name: name, Input: raw}},
+			},
+			FinishReason: "tool_use",
+		}},
+		Usage: models.MessageUsage{CompletionTokens: estimateText(string(raw))},
+	}
+}
+
+// Echo responds with the text of the last user message.
+func Echo(req *models.MessageRequest) Response {
+	text := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == models.UserRole {
+			text = req.Messages[i].Content
+			break
+		}
+	}
+	return Response{Message: TextMessage(text)}
+}
+
+// Requests returns every request received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// MessageRequests returns the decoded bodies of the /v1/messages requests received so far.
+func (s *Server) MessageRequests() []*models.MessageRequest {
+	var reqs []*models.MessageRequest
+	for _, req := range s.Requests() {
+		if req.Path == "/v1/messages" && req.Message != nil {
+			reqs = append(reqs, req.Message)
+		}
+	}
+	return reqs
+}
+
+// LastMessageRequest returns the most recent /v1/messages request body, or nil.
+func (s *Server) LastMessageRequest() *models.MessageRequest {
+	reqs := s.MessageRequests()
+	if len(reqs) == 0 {
+		return nil
+	}
+	return reqs[len(reqs)-1]
+}
+
+// AssertRequestCount fails t unless exactly n requests were made to path.
+func (s *Server) AssertRequestCount(t testing.TB, path string, n int) {
+	t.Helper()
+	count := 0
+	for _, req := range s.Requests() {
+		if req.Path == path {
+			count++
+		}
+	}
+	if count != n {
+		t.Errorf("expected %d requests to %s, got %d", n, path, count)
+	}
+}
+
+// AssertQueueEmpty fails t if scripted responses were never served.
+func (s *Server) AssertQueueEmpty(t testing.TB) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) > 0 {
+		t.Errorf("expected all scripted responses to be served, %d left", len(s.queue))
+	}
+}
+
+// record stores the request and decodes a message body if present.
+func (s *Server) record(r *http.Request) (*models.MessageRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	req := Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body}
+	var message *models.MessageRequest
+	if r.URL.Path == "/v1/messages" || r.URL.Path == "/v1/messages/count_tokens" {
+		message = &models.MessageRequest{}
+		if err := json.Unmarshal(body, message); err != nil {
+			return nil, err
+		}
+		req.Message = message
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	return message, nil
+}
+
+// next pops the next scripted response or computes one.
+func (s *Server) next(req *models.MessageRequest) Response {
+	s.mu.Lock()
+	if len(s.queue) > 0 {
+		resp := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		return resp
+	}
+	respond := s.Respond
+	s.mu.Unlock()
+	return respond(req)
+}
+
+func (s *Server) newID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idCounter++
+	return fmt.Sprintf("%s_test_%03d", prefix, s.idCounter)
+}
+
+// wait sleeps for the configured latency, returning false if the client went away.
+func (s *Server) wait(r *http.Request, extra time.Duration) bool {
+	d := s.Latency + extra
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, &Error{StatusCode: http.StatusMethodNotAllowed, Type: "invalid_request_error", Message: "method not allowed"})
+		return
+	}
+	req, err := s.record(r)
+	if err != nil {
+		writeError(w, InvalidRequestError(err.Error()))
+		return
+	}
+	resp := s.next(req)
+	if !s.wait(r, resp.Latency) {
+		return
+	}
+	if resp.Error != nil {
+		writeError(w, resp.Error)
+		return
+	}
+	message := s.prepare(req, resp.Message)
+	if req.Stream {
+		s.stream(w, r, message, resp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(message)
+}
+
+// prepare fills in the ID, model and input usage of a scripted message.
+func (s *Server) prepare(req *models.MessageRequest, message *models.MessageResponse) *models.MessageResponse {
+	if message == nil {
+		message = TextMessage("")
+	}
+	prepared := *message
+	if prepared.ID == "" {
+		prepared.ID = s.newID("msg")
+	}
+	if prepared.Object == "" {
+		prepared.Object = "message"
+	}
+	if req.Model != "" {
+		prepared.Model = req.Model
+	}
+	if prepared.Usage.PromptTokens == 0 {
+		prepared.Usage.PromptTokens = s.Tokens(req)
+	}
+	prepared.Usage.TotalTokens = prepared.Usage.PromptTokens + prepared.Usage.CompletionTokens
+	return &prepared
+}
+
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, message *models.MessageResponse, resp Response) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	send := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format, args...)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	chunks := streams.MessageChunks(message, s.ChunkSize)
+	for i, chunk := range chunks {
+		if !s.pause(r, resp.ChunkDelay, send) {
+			return
+		}
+		if resp.StreamError != nil && i == resp.StreamErrorAfter {
+			send("event: error\ndata: %s\n\n", resp.StreamError.body())
+			return
+		}
+		// Name events like the API does: deltas of content, then the
+		// message_delta that carries the finish reasons and usage.
+		event := "content_block_delta"
+		if i == len(chunks)-1 {
+			event = "message_delta"
+		}
+		data, _ := json.Marshal(chunk)
+		send("event: %s\ndata: %s\n\n", event, data)
+	}
+}
+
+// pause waits for d, sending ping events every PingInterval. It returns false
+// if the client went away.
+func (s *Server) pause(r *http.Request, d time.Duration, send func(format string, args ...interface{})) bool {
+	if d <= 0 {
+		return true
+	}
+	done := time.NewTimer(d)
+	defer done.Stop()
+	var ping <-chan time.Time
+	if s.PingInterval > 0 {
+		ticker := time.NewTicker(s.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		select {
+		case <-done.C:
+			return true
+		case <-ping:
+			send("event: ping\ndata: {\"type\": \"ping\"}\n\n")
+		case <-r.Context().Done():
+			return false
+		}
+	}
+}
+
+func (s *Server) handleCountTokens(w http.ResponseWriter, r *http.Request) {
+	req, err := s.record(r)
+	if err != nil {
+		writeError(w, InvalidRequestError(err.Error()))
+		return
+	}
+	if !s.wait(r, 0) {
+		return
+	}
+	writeJSON(w, models.CountTokensResponse{InputTokens: s.Tokens(req)})
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.record(r); err != nil {
+		writeError(w, InvalidRequestError(err.Error()))
+		return
+	}
+	if !s.wait(r, 0) {
+		return
+	}
+	s.mu.Lock()
+	list := models.ModelList{Models: append([]models.Model(nil), s.Models...), Object: "list", Total: len(s.Models)}
+	s.mu.Unlock()
+	writeJSON(w, list)
+}
+
+// handleCreateBatch processes every request of the batch immediately, so the
+// batch has ended by the time it is returned.
+func (s *Server) handleCreateBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, InvalidRequestError(err.Error()))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if _, err := s.record(r); err != nil {
+		writeError(w, InvalidRequestError(err.Error()))
+		return
+	}
+	var create models.CreateBatchRequest
+	if err := json.Unmarshal(body, &create); err != nil {
+		writeError(w, InvalidRequestError(err.Error()))
+		return
+	}
+
+	id := s.newID("msgbatch")
+	batch := &models.Batch{
+		ID:               id,
+		Type:             "message_batch",
+		ProcessingStatus: models.BatchEnded,
+		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+		EndedAt:          time.Now().UTC().Format(time.RFC3339),
+		ResultsURL:       s.URL + "/v1/messages/batches/" + id + "/results",
+	}
+	var results []models.BatchResult
+	for _, item := range create.Requests {
+		params := item.Params
+		resp := s.next(&params)
+		result := models.BatchResult{CustomID: item.CustomID}
+		if resp.Error != nil {
+			result.Result = models.BatchResultOutcome{Type: "errored", Error: &models.BatchError{Type: resp.Error.Type, Message: resp.Error.Message}}
+			batch.RequestCounts.Errored++
+		} else {
+			result.Result = models.BatchResultOutcome{Type: "succeeded", Message: s.prepare(&params, resp.Message)}
+			batch.RequestCounts.Succeeded++
+		}
+		results = append(results, result)
+	}
+
+	s.mu.Lock()
+	s.batches[id] = batch
+	s.results[id] = results
+	s.mu.Unlock()
+	writeJSON(w, batch)
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.record(r); err != nil {
+		writeError(w, InvalidRequestError(err.Error()))
+		return
+	}
+	rest := strings.TrimPrefix(r.URL.Path, "/v1/messages/batches/")
+	id, suffix, _ := strings.Cut(rest, "/")
+
+	s.mu.Lock()
+	batch, ok := s.batches[id]
+	results := s.results[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, &Error{StatusCode: http.StatusNotFound, Type: "not_found_error", Message: "batch not found"})
+		return
+	}
+
+	switch suffix {
+	case "":
+		writeJSON(w, batch)
+	case "results":
+		w.Header().Set("Content-Type", "application/x-jsonl")
+		encoder := json.NewEncoder(w)
+		for _, result := range results {
+			_ = encoder.Encode(result)
+		}
+	default:
+		writeError(w, &Error{StatusCode: http.StatusNotFound, Type: "not_found_error", Message: "not found"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, e *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	if e.RetryAfter > 0 {
+		w.Header().Set("retry-after", fmt.Sprintf("%d", int(e.RetryAfter.Seconds())))
+	}
+	w.WriteHeader(e.StatusCode)
+	_, _ = w.Write(e.body())
+}
+
+// estimateTokens approximates the input tokens of req at four characters per token.
+func estimateTokens(req *models.MessageRequest) int {
+	n := 0
+	for _, message := range req.Messages {
+		n += estimateText(message.Content)
+		for _, block := range message.Blocks {
+			n += estimateText(string(block.Input)) + estimateText(block.Content)
+		}
+	}
+	return n
+}
+
+func estimateText(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,348 @@
+// pkg/api/bulk.go
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/internal/constants"
+	apierrors "github.com/Aanthord/go-anthropic/pkg/internal/errors"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// BulkResult is the outcome of one request run by a BulkExecutor.
+type BulkResult struct {
+	// Index is the position of the request in the input.
+	Index    int
+	Response *models.MessageResponse
+	Err      error
+	// Attempts is the number of calls made for the request. It is zero for
+	// a request restored from the checkpoint.
+	Attempts int
+	// Resumed reports whether the response was restored from the checkpoint.
+	Resumed bool
+}
+
+// BulkProgress is reported after every finished request.
+type BulkProgress struct {
+	// Total is the number of requests, or zero when reading from a channel.
+	Total     int
+	Completed int
+	Failed    int
+	Resumed   int
+}
+
+// Checkpoint records the responses of finished requests so that an
+// interrupted run can resume without repeating them. Requests are identified
+// by their index, so resume with the same input in the same order.
+type Checkpoint interface {
+	Load() (map[int]*models.MessageResponse, error)
+	Save(index int, resp *models.MessageResponse) error
+}
+
+// BulkExecutor runs many message requests concurrently. Calls go through
+// the Client, so its rate limiter, retrier and middleware apply to each
+// of them; the executor adds retries of whole calls on top. A request may
+// therefore be sent up to MaxAttempts times the client's attempts, e.g. 9
+// times with the default retrier. Add WithRequestMaxRetries(0) to Options to
+// leave retries to the executor alone.
+type BulkExecutor struct {
+	Client MessageClient
+	// Concurrency is the maximum number of calls in flight.
+	Concurrency int
+	// MaxAttempts is the number of calls made for a request before its
+	// error is reported.
+	MaxAttempts int
+	// MinRetryDelay and MaxRetryDelay bound the exponential backoff
+	// between attempts. Zero values use the client defaults.
+	MinRetryDelay time.Duration
+	MaxRetryDelay time.Duration
+	// Retryable decides whether a failed attempt is retried.
+	Retryable func(err error) bool
+	// Checkpoint, when set, saves every response and skips requests it
+	// already holds.
+	Checkpoint Checkpoint
+	// OnProgress is called after every finished request. Calls are not
+	// concurrent.
+	OnProgress func(BulkProgress)
+	// Options are applied to every call.
+	Options []RequestOption
+}
+
+// NewBulkExecutor creates a BulkExecutor that runs up to concurrency calls
+// at a time and makes up to three attempts per request.
+func NewBulkExecutor(client MessageClient, concurrency int) *BulkExecutor {
+	return &BulkExecutor{
+		Client:        client,
+		Concurrency:   concurrency,
+		MaxAttempts:   3,
+		MinRetryDelay: constants.MinRetryDelay,
+		MaxRetryDelay: constants.MaxRetryDelay,
+		Retryable:     IsRetryable,
+	}
+}
+
+// IsRetryable reports whether err may succeed when the call is repeated:
+// rate limits, timeouts, overloaded or failing servers and transport errors.
+// Cancellation and validation errors are not retryable.
+func IsRetryable(err error) bool {
+	var apiErr apierrors.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+			return true
+		}
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	var validation models.ValidationErrors
+	return err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.As(err, &validation)
+}
+
+// Run runs requests and returns their results in input order. When ctx is
+// done, requests that were not started fail with its error, which Run also
+// returns.
+func (e *BulkExecutor) Run(ctx context.Context, requests []*models.MessageRequest) ([]BulkResult, error) {
+	in := make(chan *models.MessageRequest)
+	go func() {
+		defer close(in)
+		for _, req := range requests {
+			select {
+			case in <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	out, err := e.run(ctx, in, len(requests))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BulkResult, 0, len(requests))
+	for result := range out {
+		results = append(results, result)
+	}
+	for i := len(results); i < len(requests); i++ {
+		results = append(results, BulkResult{Index: i, Err: ctx.Err()})
+	}
+	return results, ctx.Err()
+}
+
+// Stream runs the requests received from requests until it is closed or ctx
+// is done. Results are sent in input order, so a slow request holds back the
+// ones after it. The results channel must be drained; it is closed after
+// the last result. The error reports a failure to load the checkpoint.
+func (e *BulkExecutor) Stream(ctx context.Context, requests <-chan *models.MessageRequest) (<-chan BulkResult, error) {
+	return e.run(ctx, requests, 0)
+}
+
+type bulkJob struct {
+	index int
+	req   *models.MessageRequest
+}
+
+func (e *BulkExecutor) run(ctx context.Context, requests <-chan *models.MessageRequest, total int) (<-chan BulkResult, error) {
+	var saved map[int]*models.MessageResponse
+	if e.Checkpoint != nil {
+		var err error
+		if saved, err = e.Checkpoint.Load(); err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+	}
+
+	jobs := make(chan bulkJob)
+	finished := make(chan BulkResult)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for index := 0; ; index++ {
+			var req *models.MessageRequest
+			var ok bool
+			select {
+			case req, ok = <-requests:
+			case <-ctx.Done():
+			}
+			if !ok {
+				return
+			}
+			if resp, ok := saved[index]; ok {
+				finished <- BulkResult{Index: index, Response: resp, Resumed: true}
+				continue
+			}
+			select {
+			case jobs <- bulkJob{index: index, req: req}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < max(e.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				finished <- e.execute(ctx, job)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	out := make(chan BulkResult)
+	go e.collect(finished, out, total)
+	return out, nil
+}
+
+// collect sends finished results to out in input order and reports progress.
+func (e *BulkExecutor) collect(finished <-chan BulkResult, out chan<- BulkResult, total int) {
+	defer close(out)
+	progress := BulkProgress{Total: total}
+	pending := make(map[int]BulkResult)
+	next := 0
+	for result := range finished {
+		progress.Completed++
+		if result.Err != nil {
+			progress.Failed++
+		}
+		if result.Resumed {
+			progress.Resumed++
+		}
+		if e.OnProgress != nil {
+			e.OnProgress(progress)
+		}
+
+		pending[result.Index] = result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			out <- result
+			next++
+		}
+	}
+}
+
+// execute calls the API for job, retrying retryable errors with backoff,
+// and saves the response to the checkpoint.
+func (e *BulkExecutor) execute(ctx context.Context, job bulkJob) BulkResult {
+	result := BulkResult{Index: job.index}
+	delay, maxDelay := e.MinRetryDelay, e.MaxRetryDelay
+	if delay <= 0 {
+		delay = constants.MinRetryDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = constants.MaxRetryDelay
+	}
+	delay = min(delay, maxDelay)
+	for {
+		result.Attempts++
+		result.Response, result.Err = e.Client.CreateMessage(ctx, job.req, e.Options...)
+		if result.Err == nil || result.Attempts >= e.MaxAttempts || e.Retryable == nil || !e.Retryable(result.Err) {
+			break
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return result
+		}
+		delay = min(2*delay, maxDelay)
+	}
+	if result.Err == nil && e.Checkpoint != nil {
+		if err := e.Checkpoint.Save(job.index, result.Response); err != nil {
+			result.Err = fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+	return result
+}
+
+// FileCheckpoint is a Checkpoint that appends one JSON line per response to
+// a file. A line cut short by a crash is ignored on Load.
+type FileCheckpoint struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenFileCheckpoint opens the checkpoint at path, creating it if needed.
+func OpenFileCheckpoint(path string) (*FileCheckpoint, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	// Terminate a line cut short by a crash so that it does not swallow the
+	// next entry.
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			_, err = file.Write([]byte{'\n'})
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+		}
+	}
+	return &FileCheckpoint{path: path, file: file}, nil
+}
+
+type checkpointEntry struct {
+	Index    int                     `json:"index"`
+	Response *models.MessageResponse `json:"response"`
+}
+
+// Load returns the responses saved so far.
+func (c *FileCheckpoint) Load() (map[int]*models.MessageResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file, err := os.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	saved := make(map[int]*models.MessageResponse)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var entry checkpointEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Response != nil {
+			saved[entry.Index] = entry.Response
+		}
+	}
+	return saved, scanner.Err()
+}
+
+// Save appends resp to the file and syncs it to disk.
+func (c *FileCheckpoint) Save(index int, resp *models.MessageResponse) error {
+	data, err := json.Marshal(checkpointEntry{Index: index, Response: resp})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return c.file.Sync()
+}
+
+// Close closes the file.
+func (c *FileCheckpoint) Close() error {
+	return c.file.Close()
+}
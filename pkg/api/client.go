@@ -5,9 +5,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Aanthord/go-anthropic/pkg/internal/constants"
@@ -23,11 +24,81 @@ type Client struct {
 	HTTPClient *http.Client
 	Logger     logging.Logger
 	Retrier    retry.Retrier
+	Middleware []Middleware
+	Redaction  RedactionPolicy
+	// AuthToken is sent as a bearer token in the Authorization header.
+	AuthToken string
+	// DefaultModel is used by message and completion requests that do not set
+	// a model.
+	DefaultModel string
+	// Betas are sent in the anthropic-beta header.
+	Betas []string
+	// StreamTimeouts bound streaming calls in place of the HTTPClient timeout.
+	StreamTimeouts StreamTimeouts
+	// TimeoutPerOutputToken scales the timeout of non-streaming message calls.
+	TimeoutPerOutputToken time.Duration
+	// ValidateRequests runs MessageRequest.Validate before sending messages.
+	ValidateRequests bool
+	// RateLimiter, when set, is waited on before every HTTP attempt.
+	RateLimiter RateLimiter
 }
 
+// RedactionPolicy controls what is masked in logged request and response bodies.
+type RedactionPolicy = logging.RedactionPolicy
+
+// DefaultRedactionPolicy masks API keys, base64 media and message content.
+var DefaultRedactionPolicy = logging.DefaultRedactionPolicy
+
+// LogLevel is the minimum severity passed to the Client's logger.
+type LogLevel = logging.Level
+
+// Log levels for WithLogLevel.
+const (
+	LogLevelDebug = logging.LevelDebug
+	LogLevelInfo  = logging.LevelInfo
+	LogLevelWarn  = logging.LevelWarn
+	LogLevelError = logging.LevelError
+)
+
 // ClientOption is a function that configures the Client.
 type ClientOption func(*Client)
 
+// WithAPIKey sets the API key for the Client.
+func WithAPIKey(apiKey string) ClientOption {
+	return func(c *Client) {
+		c.APIKey = apiKey
+	}
+}
+
+// WithAuthToken sets a bearer token sent instead of, or in addition to, the API key.
+func WithAuthToken(token string) ClientOption {
+	return func(c *Client) {
+		c.AuthToken = token
+	}
+}
+
+// WithBaseURL sets the base URL for the Client.
+func WithBaseURL(url string) ClientOption {
+	return func(c *Client) {
+		c.BaseURL = url
+	}
+}
+
+// WithDefaultModel sets the model used by message and completion requests
+// that do not set one.
+func WithDefaultModel(model string) ClientOption {
+	return func(c *Client) {
+		c.DefaultModel = model
+	}
+}
+
+// WithBetas enables beta features by sending their flags in the anthropic-beta header.
+func WithBetas(betas ...string) ClientOption {
+	return func(c *Client) {
+		c.Betas = append(c.Betas, betas...)
+	}
+}
+
 // WithHTTPClient sets the HTTP client for the Client.
 func WithHTTPClient(httpClient *http.Client) ClientOption {
 	return func(c *Client) {
@@ -42,6 +113,30 @@ func WithLogger(logger logging.Logger) ClientOption {
 	}
 }
 
+// WithSlogLogger sets a log/slog backed logger for the Client. Messages and
+// structured fields are redacted with policy.
+func WithSlogLogger(logger *slog.Logger, policy RedactionPolicy) ClientOption {
+	return func(c *Client) {
+		c.Logger = logging.NewSlogLogger(logger, policy)
+	}
+}
+
+// WithLogLevel drops messages below level. It wraps the logger configured so
+// far, so it must come after WithLogger.
+func WithLogLevel(level LogLevel) ClientOption {
+	return func(c *Client) {
+		c.Logger = logging.NewLevelLogger(c.Logger, level)
+	}
+}
+
+// WithRedaction sets the policy used to mask request and response bodies
+// before they are logged.
+func WithRedaction(policy RedactionPolicy) ClientOption {
+	return func(c *Client) {
+		c.Redaction = policy
+	}
+}
+
 // WithRetrier sets the retrier for the Client.
 func WithRetrier(retrier retry.Retrier) ClientOption {
 	return func(c *Client) {
@@ -49,6 +144,14 @@ func WithRetrier(retrier retry.Retrier) ClientOption {
 	}
 }
 
+// WithValidation validates message requests before they are sent, failing
+// with models.ValidationErrors instead of a 400 from the API.
+func WithValidation() ClientOption {
+	return func(c *Client) {
+		c.ValidateRequests = true
+	}
+}
+
 // NewClient creates a new instance of the Client with the provided API key and options.
 func NewClient(apiKey string, opts ...ClientOption) *Client {
 	client := &Client{
@@ -57,8 +160,11 @@ func NewClient(apiKey string, opts ...ClientOption) *Client {
 		HTTPClient: &http.Client{
 			Timeout: constants.DefaultTimeout,
 		},
-		Logger:  logging.NewNopLogger(),
-		Retrier: retry.NewExponentialBackoffRetrier(constants.MaxRetries, constants.MinRetryDelay, constants.MaxRetryDelay),
+		Logger:                logging.NewNopLogger(),
+		Retrier:               retry.NewExponentialBackoffRetrier(constants.MaxRetries, constants.MinRetryDelay, constants.MaxRetryDelay),
+		Redaction:             DefaultRedactionPolicy,
+		StreamTimeouts:        DefaultStreamTimeouts,
+		TimeoutPerOutputToken: constants.TimeoutPerOutputToken,
 	}
 
 	for _, opt := range opts {
@@ -75,7 +181,7 @@ func (c *Client) Get(path string) (*http.Response, error) {
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Set("X-API-Key", c.APIKey)
+		c.setAuthHeaders(req)
 		c.Logger.Debugf("Making GET request to %s", req.URL)
 		return c.HTTPClient.Do(req)
 	})
@@ -92,18 +198,32 @@ func (c *Client) Post(path string, body interface{}) (*http.Response, error) {
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Set("X-API-Key", c.APIKey)
+		c.setAuthHeaders(req)
 		req.Header.Set("Content-Type", "application/json")
-		c.Logger.Debugf("Making POST request to %s with body %s", req.URL, string(jsonBody))
+		c.Logger.Debugf("Making POST request to %s with body %s", req.URL, redactedJSON(c.Redaction, jsonBody))
 		return c.HTTPClient.Do(req)
 	})
 }
 
+// setAuthHeaders sets the credential and beta headers on req.
+func (c *Client) setAuthHeaders(req *http.Request) {
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+	if len(c.Betas) > 0 {
+		req.Header.Set("anthropic-beta", strings.Join(c.Betas, ","))
+	}
+}
+
 // handleResponse centrally handles the response parsing and error handling.
-func (c *Client) handleResponse(resp *http.Response, v interface{}) error {
+// It logs to logger, which carries the fields of the call.
+func (c *Client) handleResponse(logger logging.Logger, resp *http.Response, v interface{}) error {
 	defer resp.Body.Close()
 
-	c.Logger.Debugf("Received response with status code: %d", resp.StatusCode)
+	logger.Debugf("Received response with status code: %d", resp.StatusCode)
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
 		body, err := ioutil.ReadAll(resp.Body)
@@ -116,13 +236,13 @@ func (c *Client) handleResponse(resp *http.Response, v interface{}) error {
 		}
 		apiErr.StatusCode = resp.StatusCode
 		apiErr.Message = string(body)
-		c.Logger.Errorf("Received API error: %v", apiErr)
+		logger.Errorf("Received API error: %v", apiErr)
 		return apiErr
 	}
 
 	if v != nil {
 		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
-			c.Logger.Errorf("Failed to decode response body: %v", err)
+			logger.Errorf("Failed to decode response body: %v", err)
 			return err
 		}
 	}
@@ -130,6 +250,29 @@ func (c *Client) handleResponse(resp *http.Response, v interface{}) error {
 	return nil
 }
 
+// redacted defers redaction of a logged value until the logger formats it, so
+// that disabled log levels cost nothing.
+type redacted struct {
+	policy RedactionPolicy
+	body   []byte
+	value  interface{}
+}
+
+func (r redacted) String() string {
+	if r.body != nil {
+		return string(r.policy.RedactJSON(r.body))
+	}
+	return r.policy.Redact(r.value)
+}
+
+func redactedJSON(policy RedactionPolicy, body []byte) redacted {
+	return redacted{policy: policy, body: body}
+}
+
+func redactedValue(policy RedactionPolicy, v interface{}) redacted {
+	return redacted{policy: policy, value: v}
+}
+
 // SetBaseURL sets the base URL for the Client.
 func (c *Client) SetBaseURL(url string) {
 	c.BaseURL = url
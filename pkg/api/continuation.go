@@ -0,0 +1,247 @@
+// pkg/api/continuation.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
+)
+
+// WithContinuation continues a message that stops with max_tokens or
+// pause_turn: the partial assistant turn is sent back as a prefill and the
+// continuation is stitched onto it, summing the usage of every request.
+// Continuation stops once maxOutputTokens output tokens have been generated
+// in total. Each follow-up request passes through the middleware chain.
+func WithContinuation(maxOutputTokens int) RequestOption {
+	return func(call *Call) {
+		call.continuationBudget = maxOutputTokens
+	}
+}
+
+// unfinished reports whether resp stopped before the end of its turn.
+func unfinished(resp *models.MessageResponse) bool {
+	return resp.StopReason().Unfinished()
+}
+
+// continuationCall returns a copy of call whose request prefills output after
+// the original messages and asks for at most budget tokens.
+func continuationCall(call *Call, output models.Message, budget int) *Call {
+	req := *call.MessageRequest()
+	req.Messages = appendAssistant(req.Messages, output)
+	if req.MaxTokens == 0 || req.MaxTokens > budget {
+		req.MaxTokens = budget
+	}
+	next := *call
+	next.Params = &req
+	next.Header = call.Header.Clone()
+	next.Attempt = 0
+	return &next
+}
+
+// appendAssistant appends output to messages as an assistant prefill,
+// extending a prefill already present at the end. Trailing whitespace is
+// trimmed, since the API rejects prefills that end with it.
+func appendAssistant(messages []models.Message, output models.Message) []models.Message {
+	messages = append([]models.Message(nil), messages...)
+	prefill := models.Message{Role: models.AssistantRole}
+	if n := len(messages); n > 0 && messages[n-1].Role == models.AssistantRole {
+		prefill = messages[n-1]
+		messages = messages[:n-1]
+	}
+
+	if len(prefill.Blocks) == 0 && len(output.Blocks) == 0 {
+		prefill.Content = strings.TrimRight(prefill.Content+output.Content, " \t\r\n")
+		return append(messages, prefill)
+	}
+
+	blocks := append([]models.ContentBlock(nil), prefill.Blocks...)
+	if len(prefill.Blocks) == 0 && prefill.Content != "" {
+		blocks = append(blocks, models.ContentBlock{Type: models.TextBlock, Text: prefill.Content})
+	}
+	if len(output.Blocks) == 0 && output.Content != "" {
+		blocks = append(blocks, models.ContentBlock{Type: models.TextBlock, Text: output.Content})
+	}
+	for _, block := range output.Blocks {
+		// Adjacent text blocks are merged so that the prefill reads as one turn.
+		if n := len(blocks); block.Type == models.TextBlock && n > 0 && blocks[n-1].Type == models.TextBlock {
+			blocks[n-1].Text += block.Text
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	if n := len(blocks); n > 0 && blocks[n-1].Type == models.TextBlock {
+		blocks[n-1].Text = strings.TrimRight(blocks[n-1].Text, " \t\r\n")
+	}
+	prefill.Blocks = blocks
+	prefill.Content = ""
+	for _, block := range blocks {
+		if block.Type == models.TextBlock {
+			prefill.Content += block.Text
+		}
+	}
+	return append(messages, prefill)
+}
+
+// addUsage adds the usage of one request to a running total.
+func addUsage(total *models.MessageUsage, usage models.MessageUsage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+	total.CacheCreationInputTokens += usage.CacheCreationInputTokens
+	total.CacheReadInputTokens += usage.CacheReadInputTokens
+}
+
+// stitch appends the output of part to stitched, adopting its finish reasons
+// and adding its usage. stitched keeps its ID.
+func stitch(stitched *models.MessageResponse, part *models.MessageResponse) {
+	for i := range stitched.Choices {
+		blocks := stitched.Choices[i].Message.Blocks
+		stitched.Choices[i].Message.Blocks = append([]models.ContentBlock(nil), blocks...)
+	}
+	id := stitched.ID
+	for _, chunk := range streams.MessageChunks(part, 0) {
+		chunk.Usage = models.MessageUsage{}
+		streams.AccumulateChunk(stitched, chunk)
+	}
+	stitched.ID = id
+	addUsage(&stitched.Usage, part.Usage)
+}
+
+// continueMessage runs a non-streaming call and its continuations.
+func (c *Client) continueMessage(ctx context.Context, call *Call, handler Handler) (*Result, error) {
+	result, err := handler(ctx, call)
+	if err != nil {
+		return result, err
+	}
+	resp := result.MessageResponse()
+	if resp == nil {
+		return result, nil
+	}
+
+	stitched := *resp
+	stitched.Choices = append([]models.MessageChoice(nil), resp.Choices...)
+	budget := call.continuationBudget - resp.Usage.CompletionTokens
+	for unfinished(&stitched) && budget > 0 {
+		c.Logger.Debugf("Continuing message after %s with %d tokens left", stitched.StopReason(), budget)
+		next, err := handler(ctx, continuationCall(call, stitched.ToAssistantMessage(), budget))
+		if err != nil {
+			return next, fmt.Errorf("failed to continue message: %w", err)
+		}
+		part := next.MessageResponse()
+		if part == nil || len(part.Choices) == 0 {
+			return next, fmt.Errorf("failed to continue message: %w", unexpectedValue(next.Value))
+		}
+		result = next
+		stitch(&stitched, part)
+
+		if part.Usage.CompletionTokens == 0 {
+			break
+		}
+		budget -= part.Usage.CompletionTokens
+	}
+
+	result.Value = &stitched
+	return result, nil
+}
+
+// continueStream runs a streaming call and its continuations as one stream.
+// Finish reasons and usage are withheld from the chunks of each request and
+// sent in a final chunk once the last request is done.
+func (c *Client) continueStream(ctx context.Context, call *Call, handler Handler) (*Result, error) {
+	result, err := handler(ctx, call)
+	if err != nil || result.Stream == nil {
+		return result, err
+	}
+
+	output := make(chan models.MessageResponse)
+	errCh := make(chan error)
+	first := *result
+	first.Stream, first.StreamErrors = output, errCh
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		var assembled models.MessageResponse
+		var usage models.MessageUsage
+		current := result
+		budget := call.continuationBudget
+		for {
+			var segment models.MessageResponse
+			failed := false
+			stream, errs := current.Stream, current.StreamErrors
+			for stream != nil || errs != nil {
+				select {
+				case chunk, ok := <-stream:
+					if !ok {
+						stream = nil
+						continue
+					}
+					streams.AccumulateChunk(&segment, chunk)
+					if chunk = withheld(chunk); len(chunk.Choices) > 0 {
+						output <- chunk
+					}
+				case err, ok := <-errs:
+					if !ok {
+						errs = nil
+						continue
+					}
+					failed = true
+					errCh <- err
+				}
+			}
+
+			id := assembled.ID
+			segmentUsage := segment.Usage
+			segment.Usage = models.MessageUsage{}
+			if len(assembled.Choices) == 0 {
+				assembled = segment
+			} else {
+				stitch(&assembled, &segment)
+				assembled.ID = id
+			}
+			addUsage(&usage, segmentUsage)
+			budget -= segmentUsage.CompletionTokens
+
+			if failed || !unfinished(&assembled) || budget <= 0 || segmentUsage.CompletionTokens == 0 {
+				break
+			}
+			c.Logger.Debugf("Continuing stream after %s with %d tokens left", assembled.StopReason(), budget)
+			next, err := handler(ctx, continuationCall(call, assembled.ToAssistantMessage(), budget))
+			if err == nil && next.Stream == nil {
+				err = unexpectedValue(next.Value)
+			}
+			if err != nil {
+				errCh <- fmt.Errorf("failed to continue stream: %w", err)
+				break
+			}
+			current = next
+		}
+
+		final := models.MessageResponse{ID: assembled.ID, Object: assembled.Object, Created: assembled.Created, Model: assembled.Model, Usage: usage}
+		for _, choice := range assembled.Choices {
+			final.Choices = append(final.Choices, models.MessageChoice{Index: choice.Index, FinishReason: choice.FinishReason})
+		}
+		output <- final
+	}()
+
+	return &first, nil
+}
+
+// withheld strips the finish reasons and usage from chunk and drops choices
+// left without content.
+func withheld(chunk models.MessageResponse) models.MessageResponse {
+	chunk.Usage = models.MessageUsage{}
+	var choices []models.MessageChoice
+	for _, choice := range chunk.Choices {
+		choice.FinishReason = ""
+		if choice.Message.Content != "" || len(choice.Message.Blocks) > 0 {
+			choices = append(choices, choice)
+		}
+	}
+	chunk.Choices = choices
+	return chunk
+}
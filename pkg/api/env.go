@@ -0,0 +1,134 @@
+// pkg/api/env.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/internal/constants"
+	"github.com/Aanthord/go-anthropic/pkg/internal/retry"
+)
+
+// Environment variables read by NewClientFromEnv.
+const (
+	EnvAPIKey     = "ANTHROPIC_API_KEY"
+	EnvAuthToken  = "ANTHROPIC_AUTH_TOKEN"
+	EnvBaseURL    = "ANTHROPIC_BASE_URL"
+	EnvTimeout    = "ANTHROPIC_TIMEOUT"
+	EnvMaxRetries = "ANTHROPIC_MAX_RETRIES"
+	EnvModel      = "ANTHROPIC_MODEL"
+	EnvBeta       = "ANTHROPIC_BETA"
+	EnvProxy      = "ANTHROPIC_PROXY"
+)
+
+// NewClientFromEnv creates a Client configured from the environment:
+//
+//	ANTHROPIC_API_KEY      API key sent as X-API-Key
+//	ANTHROPIC_AUTH_TOKEN   bearer token sent as Authorization
+//	ANTHROPIC_BASE_URL     API base URL
+//	ANTHROPIC_TIMEOUT      request timeout, e.g. "30s" or a number of seconds
+//	ANTHROPIC_MAX_RETRIES  retries after the first attempt
+//	ANTHROPIC_MODEL        model used by requests that do not set one
+//	ANTHROPIC_BETA         comma-separated beta flags sent as anthropic-beta
+//	ANTHROPIC_PROXY        proxy URL; HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+//	                       are honoured when it is unset
+//
+// opts are applied after the environment and take precedence. An error lists
+// every invalid variable, or reports that neither an API key nor an auth
+// token was provided.
+func NewClientFromEnv(opts ...ClientOption) (*Client, error) {
+	envOpts, err := optionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(os.Getenv(EnvAPIKey), append(envOpts, opts...)...)
+	if client.APIKey == "" && client.AuthToken == "" {
+		return nil, fmt.Errorf("no credentials: set %s or %s", EnvAPIKey, EnvAuthToken)
+	}
+	return client, nil
+}
+
+func optionsFromEnv() ([]ClientOption, error) {
+	var opts []ClientOption
+	var errs []error
+	invalid := func(name, value, reason string) {
+		errs = append(errs, fmt.Errorf("invalid %s %q: %s", name, value, reason))
+	}
+
+	if token := os.Getenv(EnvAuthToken); token != "" {
+		opts = append(opts, WithAuthToken(token))
+	}
+	if baseURL := os.Getenv(EnvBaseURL); baseURL != "" {
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid(EnvBaseURL, baseURL, "must be an absolute http or https URL")
+		} else {
+			opts = append(opts, WithBaseURL(strings.TrimSuffix(baseURL, "/")))
+		}
+	}
+	if model := os.Getenv(EnvModel); model != "" {
+		opts = append(opts, WithDefaultModel(model))
+	}
+	if beta := os.Getenv(EnvBeta); beta != "" {
+		var betas []string
+		for _, flag := range strings.Split(beta, ",") {
+			if flag = strings.TrimSpace(flag); flag != "" {
+				betas = append(betas, flag)
+			}
+		}
+		opts = append(opts, WithBetas(betas...))
+	}
+
+	timeout := constants.DefaultTimeout
+	if value := os.Getenv(EnvTimeout); value != "" {
+		d, err := parseTimeout(value)
+		if err != nil {
+			invalid(EnvTimeout, value, err.Error())
+		}
+		timeout = d
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy := os.Getenv(EnvProxy); proxy != "" {
+		if u, err := url.Parse(proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid(EnvProxy, proxy, "must be an absolute URL")
+		} else {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+	opts = append(opts, WithHTTPClient(&http.Client{Timeout: timeout, Transport: transport}))
+
+	if value := os.Getenv(EnvMaxRetries); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			invalid(EnvMaxRetries, value, "must be a non-negative integer")
+		} else {
+			opts = append(opts, WithRetrier(retry.NewExponentialBackoffRetrier(n+1, constants.MinRetryDelay, constants.MaxRetryDelay)))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid client configuration: %w", errors.Join(errs...))
+	}
+	return opts, nil
+}
+
+// parseTimeout accepts a Go duration or a number of seconds.
+func parseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil {
+			return 0, errors.New("must be a duration such as 30s or a number of seconds")
+		}
+		d = time.Duration(seconds * float64(time.Second))
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	return d, nil
+}
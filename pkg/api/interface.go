@@ -0,0 +1,19 @@
+// pkg/api/interface.go
+package api
+
+import (
+	"context"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// MessageClient is the subset of Client used to generate messages. Depend on
+// it instead of *Client so that code can be tested with a fake such as
+// anthropictest.Fake.
+type MessageClient interface {
+	CreateMessage(ctx context.Context, req *models.MessageRequest, opts ...RequestOption) (*models.MessageResponse, error)
+	StreamMessages(ctx context.Context, req *models.MessageRequest, opts ...RequestOption) (<-chan models.MessageResponse, <-chan error)
+	CountTokens(ctx context.Context, req *models.MessageRequest, opts ...RequestOption) (*models.CountTokensResponse, error)
+}
+
+var _ MessageClient = (*Client)(nil)
@@ -4,43 +4,64 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/Aanthord/go-anthropic/pkg/models"
-	"github.com/Aanthord/go-anthropic/pkg/streams"
 )
 
 // CreateMessage creates a message using the provided request.
-func (c *Client) CreateMessage(ctx context.Context, req *models.MessageRequest) (*models.MessageResponse, error) {
-	c.Logger.Debugf("Creating message with request: %+v", req)
-	resp, err := c.Post("/v1/messages", req)
+func (c *Client) CreateMessage(ctx context.Context, req *models.MessageRequest, opts ...RequestOption) (*models.MessageResponse, error) {
+	c.Logger.Debugf("Creating message with request: %s", redactedValue(c.Redaction, req))
+	result, err := c.invoke(ctx, &Call{
+		Operation: "CreateMessage",
+		Method:    http.MethodPost,
+		Path:      "/v1/messages",
+		Params:    req,
+		newValue:  func() interface{} { return &models.MessageResponse{} },
+	}, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create message: %w", err)
 	}
-	var messageResp models.MessageResponse
-	if err := c.handleResponse(resp, &messageResp); err != nil {
-		return nil, fmt.Errorf("failed to handle message response: %w", err)
+	messageResp := result.MessageResponse()
+	if messageResp == nil {
+		return nil, fmt.Errorf("failed to create message: %w", unexpectedValue(result.Value))
 	}
-	return &messageResp, nil
+	return messageResp, nil
 }
 
 // StreamMessages streams messages using the provided request.
-func (c *Client) StreamMessages(ctx context.Context, req *models.MessageRequest) (<-chan models.MessageResponse, <-chan error) {
+func (c *Client) StreamMessages(ctx context.Context, req *models.MessageRequest, opts ...RequestOption) (<-chan models.MessageResponse, <-chan error) {
 	req.Stream = true
-	c.Logger.Debugf("Streaming messages with request: %+v", req)
-	resp, err := c.Post("/v1/messages", req)
+	c.Logger.Debugf("Streaming messages with request: %s", redactedValue(c.Redaction, req))
+	result, err := c.invoke(ctx, &Call{
+		Operation: "StreamMessages",
+		Method:    http.MethodPost,
+		Path:      "/v1/messages",
+		Params:    req,
+		Stream:    true,
+	}, opts)
 	if err != nil {
-		err := fmt.Errorf("failed to stream messages: %w", err)
-		errCh := make(chan error, 1)
-		errCh <- err
-		return nil, errCh
+		return errorStream(fmt.Errorf("failed to stream messages: %w", err))
 	}
-	if resp.StatusCode != http.StatusOK {
-		err := errors.APIError{StatusCode: resp.StatusCode}
-		errCh := make(chan error, 1)
-		errCh <- err
-		return nil, errCh
+	return result.Stream, result.StreamErrors
+}
+
+// CountTokens counts the input tokens of req without creating a message.
+func (c *Client) CountTokens(ctx context.Context, req *models.MessageRequest, opts ...RequestOption) (*models.CountTokensResponse, error) {
+	c.Logger.Debugf("Counting tokens with request: %s", redactedValue(c.Redaction, req))
+	result, err := c.invoke(ctx, &Call{
+		Operation: "CountTokens",
+		Method:    http.MethodPost,
+		Path:      "/v1/messages/count_tokens",
+		Params:    req,
+		newValue:  func() interface{} { return &models.CountTokensResponse{} },
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	count, ok := result.Value.(*models.CountTokensResponse)
+	if !ok {
+		return nil, fmt.Errorf("failed to count tokens: %w", unexpectedValue(result.Value))
 	}
-	eventStream := streams.ConsumeStream(resp.Body)
-	messageStream, errStream := streams.MessageStreamConverter(eventStream)
-	return messageStream, errStream
+	return count, nil
 }
@@ -0,0 +1,363 @@
+// pkg/api/middleware.go
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/internal/errors"
+	"github.com/Aanthord/go-anthropic/pkg/internal/logging"
+	"github.com/Aanthord/go-anthropic/pkg/internal/retry"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
+)
+
+// Call describes a single API call as it passes through the middleware chain.
+// Middleware may mutate any field before passing the call on.
+type Call struct {
+	// Operation is the name of the client method, e.g. "CreateMessage".
+	Operation string
+	Method    string
+	Path      string
+	// Params is the decoded request body, e.g. *models.MessageRequest.
+	// It is nil for requests without a body.
+	Params interface{}
+	// Header holds extra headers set on the outgoing HTTP request.
+	Header http.Header
+	// Stream reports whether the response is a server-sent event stream.
+	Stream bool
+	// Attempt counts how many times the call has reached the transport.
+	Attempt int
+
+	// BaseURL, Query and Betas override or extend the client's settings
+	// for this call. See RequestOption.
+	BaseURL string
+	Query   url.Values
+	Betas   []string
+	// Timeout replaces the HTTPClient timeout of each attempt when non-zero.
+	Timeout time.Duration
+	// StreamTimeouts replaces the client's StreamTimeouts when non-nil.
+	StreamTimeouts *StreamTimeouts
+	// Retrier replaces the client's Retrier when non-nil.
+	Retrier retry.Retrier
+	// ExtraBody holds top-level JSON fields merged into the encoded Params.
+	ExtraBody map[string]interface{}
+	// Labels annotate the call in logs and trace spans, e.g. with the prompt
+	// template that produced it. They are not sent to the API.
+	Labels map[string]string
+
+	newValue           func() interface{}
+	responseMetadata   *ResponseMetadata
+	continuationBudget int
+}
+
+// MessageRequest returns the call parameters as a message request, or nil.
+func (c *Call) MessageRequest() *models.MessageRequest {
+	req, _ := c.Params.(*models.MessageRequest)
+	return req
+}
+
+// CompletionRequest returns the call parameters as a completion request, or nil.
+func (c *Call) CompletionRequest() *models.CompletionRequest {
+	req, _ := c.Params.(*models.CompletionRequest)
+	return req
+}
+
+// Result is the outcome of a Call.
+type Result struct {
+	// HTTPRequest is the last request sent to the transport.
+	HTTPRequest *http.Request
+	// HTTPResponse is the raw response. For non-streaming calls its body
+	// has already been consumed and closed.
+	HTTPResponse *http.Response
+	// Value is the decoded response body, e.g. *models.MessageResponse.
+	Value interface{}
+	// Stream and StreamErrors carry the events of a streaming call.
+	// Middleware may replace them to observe or transform events.
+	Stream       <-chan models.MessageResponse
+	StreamErrors <-chan error
+	// CompletionStream replaces Stream for streaming completion calls.
+	CompletionStream <-chan models.CompletionResponse
+	// Metadata describes HTTPResponse. It is nil when no request was sent.
+	Metadata *ResponseMetadata
+}
+
+// MessageResponse returns the decoded value as a message response, or nil.
+func (r *Result) MessageResponse() *models.MessageResponse {
+	resp, _ := r.Value.(*models.MessageResponse)
+	return resp
+}
+
+// Handler sends a Call and returns its Result.
+type Handler func(ctx context.Context, call *Call) (*Result, error)
+
+// Middleware intercepts a Call. It may inspect or mutate the call, return a
+// Result without calling next to short-circuit, or call next more than once
+// to retry.
+type Middleware func(ctx context.Context, call *Call, next Handler) (*Result, error)
+
+// WithMiddleware appends middleware to the Client. Middleware runs in the order
+// it was added on the way in and in reverse order on the way out.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.Middleware = append(c.Middleware, middleware...)
+	}
+}
+
+// invoke applies opts to the call and runs it through the middleware chain and
+// the transport. Message and completion requests without a model get the
+// client's DefaultModel.
+// With ValidateRequests set, invalid message requests fail before the chain runs.
+func (c *Client) invoke(ctx context.Context, call *Call, opts []RequestOption) (*Result, error) {
+	if call.Header == nil {
+		call.Header = make(http.Header)
+	}
+	for _, opt := range opts {
+		opt(call)
+	}
+	// The default model is set on a copy so that the caller's request is
+	// left as it was.
+	if req := call.MessageRequest(); req != nil && req.Model == "" && c.DefaultModel != "" {
+		copied := *req
+		copied.Model = c.DefaultModel
+		call.Params = &copied
+	}
+	if req := call.CompletionRequest(); req != nil && req.Model == "" && c.DefaultModel != "" {
+		copied := *req
+		copied.Model = c.DefaultModel
+		call.Params = &copied
+	}
+	if req := call.MessageRequest(); req != nil && c.ValidateRequests && call.Operation != "CountTokens" {
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	handler := Handler(c.send)
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		mw, next := c.Middleware[i], handler
+		handler = func(ctx context.Context, call *Call) (*Result, error) {
+			return mw(ctx, call, next)
+		}
+	}
+	var result *Result
+	var err error
+	switch {
+	case call.continuationBudget > 0 && call.MessageRequest() != nil && call.Stream:
+		result, err = c.continueStream(ctx, call, handler)
+	case call.continuationBudget > 0 && call.MessageRequest() != nil:
+		result, err = c.continueMessage(ctx, call, handler)
+	default:
+		result, err = handler(ctx, call)
+	}
+	if call.responseMetadata != nil && result != nil && result.Metadata != nil {
+		*call.responseMetadata = *result.Metadata
+	}
+	return result, err
+}
+
+// send is the innermost Handler. It encodes the call, sends it with retries and
+// decodes the response.
+func (c *Client) send(ctx context.Context, call *Call) (*Result, error) {
+	call.Attempt++
+	result := &Result{}
+	start := time.Now()
+
+	jsonBody, err := encodeBody(call.Params, call.ExtraBody)
+	if err != nil {
+		return nil, err
+	}
+	target := c.callURL(call)
+	logger := logging.With(c.Logger, labelFields(call.Labels)...)
+	retrier, httpClient := c.Retrier, c.HTTPClient
+	if call.Retrier != nil {
+		retrier = call.Retrier
+	}
+	if timeout := c.attemptTimeout(call); timeout != httpClient.Timeout {
+		copied := *c.HTTPClient
+		copied.Timeout = timeout
+		httpClient = &copied
+	}
+	var streamTimeouts StreamTimeouts
+	if call.Stream {
+		streamTimeouts = c.streamTimeouts(call)
+	}
+
+	attempts := 0
+	var cancelAttempt context.CancelCauseFunc
+	resp, err := retrier.Do(func() (*http.Response, error) {
+		attempts++
+		// Each attempt gets its own context so that a stream can be aborted
+		// after Do returns without affecting the caller's context.
+		if cancelAttempt != nil {
+			cancelAttempt(nil)
+		}
+		if c.RateLimiter != nil {
+			if err := c.RateLimiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		attemptCtx, cancel := context.WithCancelCause(ctx)
+		cancelAttempt = cancel
+		if jsonBody != nil {
+			logger.Debugf("Making %s request to %s with body %s", call.Method, target, redactedJSON(c.Redaction, jsonBody))
+		} else {
+			logger.Debugf("Making %s request to %s", call.Method, target)
+		}
+		req, err := http.NewRequestWithContext(attemptCtx, call.Method, target, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, err
+		}
+		c.setAuthHeaders(req)
+		if len(call.Betas) > 0 {
+			req.Header.Set("anthropic-beta", strings.Join(append(append([]string(nil), c.Betas...), call.Betas...), ","))
+		}
+		if jsonBody != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for key, values := range call.Header {
+			req.Header[key] = values
+		}
+		result.HTTPRequest = req
+		if streamTimeouts.Connect > 0 {
+			timer := time.AfterFunc(streamTimeouts.Connect, func() {
+				cancel(fmt.Errorf("stream connect timeout: no response within %s", streamTimeouts.Connect))
+			})
+			defer timer.Stop()
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			if cause := context.Cause(attemptCtx); cause != nil && ctx.Err() == nil {
+				err = cause
+			}
+			cancel(nil)
+		}
+		return resp, err
+	})
+	if err != nil {
+		logger.Errorf("Failed %s %s: %v", call.Method, call.Path, err)
+		if attempts == 0 {
+			return nil, err
+		}
+		result.Metadata = &ResponseMetadata{Latency: time.Since(start), Attempts: attempts}
+		return result, err
+	}
+	if resp == nil {
+		// A retrier that makes no attempt returns neither a response nor an
+		// error.
+		return nil, fmt.Errorf("retrier returned no response after %d attempts", attempts)
+	}
+	if !call.Stream {
+		defer cancelAttempt(nil)
+	}
+	result.HTTPResponse = resp
+	result.Metadata = ParseResponseMetadata(resp)
+	result.Metadata.Latency = time.Since(start)
+	result.Metadata.Attempts = attempts
+
+	model := ""
+	if req := call.MessageRequest(); req != nil {
+		model = req.Model
+	}
+	fields := []interface{}{
+		"request_id", result.Metadata.RequestID,
+		"model", model,
+		"status", resp.StatusCode,
+		"latency", result.Metadata.Latency,
+	}
+	logging.With(logger, fields...).Debugf("Completed %s %s", call.Method, call.Path)
+
+	if call.Stream {
+		if resp.StatusCode != http.StatusOK {
+			defer cancelAttempt(nil)
+			if err := c.handleResponse(logger, resp, nil); err != nil {
+				return result, err
+			}
+			return result, errors.APIError{StatusCode: resp.StatusCode}
+		}
+		abort := func() { cancelAttempt(nil) }
+		eventStream := streams.WatchStream(streams.ConsumeStream(resp.Body), streamTimeouts.FirstEvent, streamTimeouts.Idle, abort)
+		if call.CompletionRequest() != nil {
+			result.CompletionStream, result.StreamErrors = streams.CompletionStreamConverter(eventStream)
+		} else {
+			result.Stream, result.StreamErrors = streams.MessageStreamConverter(eventStream)
+		}
+		return result, nil
+	}
+
+	var value interface{}
+	if call.newValue != nil {
+		value = call.newValue()
+	}
+	if err := c.handleResponse(logger, resp, value); err != nil {
+		return result, err
+	}
+	result.Value = value
+	return result, nil
+}
+
+// callURL returns the full URL of the call, including extra query parameters.
+func (c *Client) callURL(call *Call) string {
+	baseURL := c.BaseURL
+	if call.BaseURL != "" {
+		baseURL = call.BaseURL
+	}
+	target := baseURL + call.Path
+	if len(call.Query) > 0 {
+		separator := "?"
+		if strings.Contains(call.Path, "?") {
+			separator = "&"
+		}
+		target += separator + call.Query.Encode()
+	}
+	return target
+}
+
+// encodeBody marshals params and merges extra into the resulting JSON object.
+// It returns nil when there is nothing to send.
+func encodeBody(params interface{}, extra map[string]interface{}) ([]byte, error) {
+	if params == nil && len(extra) == 0 {
+		return nil, nil
+	}
+	fields := make(map[string]interface{})
+	if params != nil {
+		jsonBody, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		if len(extra) == 0 {
+			return jsonBody, nil
+		}
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(jsonBody, &raw); err != nil {
+			return nil, fmt.Errorf("extra body fields require a JSON object body: %w", err)
+		}
+		for key, value := range raw {
+			fields[key] = value
+		}
+	}
+	for key, value := range extra {
+		fields[key] = value
+	}
+	return json.Marshal(fields)
+}
+
+// errorStream returns closed channels that report err, in the shape returned by
+// the streaming methods.
+func errorStream(err error) (<-chan models.MessageResponse, <-chan error) {
+	errCh := make(chan error, 1)
+	errCh <- err
+	close(errCh)
+	return nil, errCh
+}
+
+// unexpectedValue reports a Result whose value has the wrong type, which can
+// only happen when middleware short-circuits with a mismatched value.
+func unexpectedValue(v interface{}) error {
+	return fmt.Errorf("unexpected result value of type %T", v)
+}
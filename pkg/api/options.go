@@ -0,0 +1,116 @@
+// pkg/api/options.go
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/internal/constants"
+	"github.com/Aanthord/go-anthropic/pkg/internal/retry"
+)
+
+// RequestOption configures a single call, overriding the client defaults.
+// Options run before the middleware chain, so middleware sees their effect.
+type RequestOption func(*Call)
+
+// WithRequestTimeout sets the timeout of each attempt of the call, replacing
+// the timeout of the client's HTTPClient.
+func WithRequestTimeout(timeout time.Duration) RequestOption {
+	return func(call *Call) {
+		call.Timeout = timeout
+	}
+}
+
+// WithRequestRetrier sets the retry policy of the call.
+func WithRequestRetrier(retrier retry.Retrier) RequestOption {
+	return func(call *Call) {
+		call.Retrier = retrier
+	}
+}
+
+// WithRequestMaxRetries retries the call up to maxRetries times after the
+// first attempt with exponential backoff. Zero or a negative value disables
+// retries.
+func WithRequestMaxRetries(maxRetries int) RequestOption {
+	return WithRequestRetrier(retry.NewExponentialBackoffRetrier(max(maxRetries, 0)+1, constants.MinRetryDelay, constants.MaxRetryDelay))
+}
+
+// WithRequestBaseURL sends the call to baseURL instead of the client's BaseURL.
+func WithRequestBaseURL(baseURL string) RequestOption {
+	return func(call *Call) {
+		call.BaseURL = baseURL
+	}
+}
+
+// WithRequestBetas enables beta features for the call in addition to the
+// client's Betas.
+func WithRequestBetas(betas ...string) RequestOption {
+	return func(call *Call) {
+		call.Betas = append(call.Betas, betas...)
+	}
+}
+
+// WithHeader sets an extra HTTP header on the call.
+func WithHeader(key, value string) RequestOption {
+	return func(call *Call) {
+		if call.Header == nil {
+			call.Header = make(http.Header)
+		}
+		call.Header.Set(key, value)
+	}
+}
+
+// WithQuery adds a query parameter to the call's URL.
+func WithQuery(key, value string) RequestOption {
+	return func(call *Call) {
+		if call.Query == nil {
+			call.Query = make(url.Values)
+		}
+		call.Query.Add(key, value)
+	}
+}
+
+// WithIdempotencyKey sets the Idempotency-Key header, which is sent unchanged
+// on every retry of the call.
+func WithIdempotencyKey(key string) RequestOption {
+	return WithHeader("Idempotency-Key", key)
+}
+
+// WithExtraBody sets a top-level JSON field of the request body, for API
+// parameters the request types do not model yet. It overrides a modeled
+// field of the same name.
+func WithExtraBody(key string, value interface{}) RequestOption {
+	return func(call *Call) {
+		if call.ExtraBody == nil {
+			call.ExtraBody = make(map[string]interface{})
+		}
+		call.ExtraBody[key] = value
+	}
+}
+
+// WithLabel annotates the call with a key and value that are added to the
+// client's log lines for it.
+func WithLabel(key, value string) RequestOption {
+	return func(call *Call) {
+		if call.Labels == nil {
+			call.Labels = make(map[string]string)
+		}
+		call.Labels[key] = value
+	}
+}
+
+// labelFields returns labels as sorted key-value logging fields.
+func labelFields(labels map[string]string) []interface{} {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fields := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		fields = append(fields, key, labels[key])
+	}
+	return fields
+}
@@ -0,0 +1,93 @@
+// pkg/api/ratelimit.go
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces the HTTP requests of a Client. Wait blocks until a
+// request may be sent or ctx is done.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimiter makes the client wait on limiter before every attempt,
+// including retries.
+func WithRateLimiter(limiter RateLimiter) ClientOption {
+	return func(c *Client) {
+		c.RateLimiter = limiter
+	}
+}
+
+// WithRateLimit limits the client to requestsPerMinute, allowing bursts of
+// up to burst requests.
+func WithRateLimit(requestsPerMinute, burst int) ClientOption {
+	return WithRateLimiter(NewRateLimiter(requestsPerMinute, burst))
+}
+
+// TokenBucket is a RateLimiter that refills at a steady rate up to a burst
+// size. It is safe for concurrent use.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a TokenBucket that allows requestsPerMinute with
+// bursts of up to burst requests. It starts full. A requestsPerMinute of
+// zero or less does not limit.
+func NewRateLimiter(requestsPerMinute, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   float64(requestsPerMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token, waiting for one to be refilled if the bucket is empty.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, possibly going into debt, and returns how long to
+// wait until the debt is repaid.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
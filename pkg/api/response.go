@@ -0,0 +1,72 @@
+// pkg/api/response.go
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is the state of one limit reported by the anthropic-ratelimit-*
+// headers. Fields are zero when the header was absent.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// ResponseMetadata describes the raw HTTP response of a call.
+type ResponseMetadata struct {
+	StatusCode int
+	Header     http.Header
+	// RequestID identifies the request when contacting support.
+	RequestID string
+	// Latency is the time from sending the request until the response
+	// headers arrived, including retries.
+	Latency time.Duration
+	// Attempts is the number of HTTP requests sent, including retries.
+	Attempts int
+	// RetryAfter is the delay requested by the server, if any.
+	RetryAfter time.Duration
+
+	Requests     RateLimit
+	Tokens       RateLimit
+	InputTokens  RateLimit
+	OutputTokens RateLimit
+}
+
+// ParseResponseMetadata extracts the request ID and rate limits from resp.
+func ParseResponseMetadata(resp *http.Response) *ResponseMetadata {
+	header := resp.Header
+	metadata := &ResponseMetadata{
+		StatusCode:   resp.StatusCode,
+		Header:       header.Clone(),
+		RequestID:    header.Get("request-id"),
+		Requests:     parseRateLimit(header, "requests"),
+		Tokens:       parseRateLimit(header, "tokens"),
+		InputTokens:  parseRateLimit(header, "input-tokens"),
+		OutputTokens: parseRateLimit(header, "output-tokens"),
+	}
+	if seconds, err := strconv.ParseFloat(header.Get("retry-after"), 64); err == nil {
+		metadata.RetryAfter = time.Duration(seconds * float64(time.Second))
+	}
+	return metadata
+}
+
+func parseRateLimit(header http.Header, name string) RateLimit {
+	prefix := "anthropic-ratelimit-" + name + "-"
+	var limit RateLimit
+	limit.Limit, _ = strconv.Atoi(header.Get(prefix + "limit"))
+	limit.Remaining, _ = strconv.Atoi(header.Get(prefix + "remaining"))
+	limit.Reset, _ = time.Parse(time.RFC3339, header.Get(prefix+"reset"))
+	return limit
+}
+
+// WithResponseMetadata stores the metadata of the call's HTTP response in dst,
+// also when the call fails with an API error. dst is left unchanged when no
+// request was sent, e.g. when middleware answered from a cache.
+func WithResponseMetadata(dst *ResponseMetadata) RequestOption {
+	return func(call *Call) {
+		call.responseMetadata = dst
+	}
+}
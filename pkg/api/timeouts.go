@@ -0,0 +1,83 @@
+// pkg/api/timeouts.go
+package api
+
+import (
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/internal/constants"
+)
+
+// StreamTimeouts bound the phases of a streaming call. A zero duration
+// disables that limit. The HTTPClient timeout does not apply to streams.
+type StreamTimeouts struct {
+	// Connect limits each attempt until the response headers arrive.
+	Connect time.Duration
+	// FirstEvent limits the wait from the response headers to the first event.
+	FirstEvent time.Duration
+	// Idle limits the silence between two events. Ping events reset it.
+	Idle time.Duration
+}
+
+// DefaultStreamTimeouts are the stream timeouts of a new Client.
+var DefaultStreamTimeouts = StreamTimeouts{
+	Connect:    constants.StreamConnectTimeout,
+	FirstEvent: constants.StreamFirstEventTimeout,
+	Idle:       constants.StreamIdleTimeout,
+}
+
+// WithStreamTimeouts sets the timeouts of streaming calls.
+func WithStreamTimeouts(timeouts StreamTimeouts) ClientOption {
+	return func(c *Client) {
+		c.StreamTimeouts = timeouts
+	}
+}
+
+// WithTimeoutPerOutputToken scales the timeout of non-streaming message calls
+// with their max_tokens: a call may take perToken for every requested token
+// when that exceeds the HTTPClient timeout, up to ten minutes. Zero disables
+// scaling.
+func WithTimeoutPerOutputToken(perToken time.Duration) ClientOption {
+	return func(c *Client) {
+		c.TimeoutPerOutputToken = perToken
+	}
+}
+
+// WithRequestStreamTimeouts sets the stream timeouts of the call.
+func WithRequestStreamTimeouts(timeouts StreamTimeouts) RequestOption {
+	return func(call *Call) {
+		call.StreamTimeouts = &timeouts
+	}
+}
+
+// attemptTimeout returns the timeout of each HTTP attempt of call: an explicit
+// request timeout, none for streams, which are bounded by StreamTimeouts, or
+// the HTTPClient timeout scaled with max_tokens.
+func (c *Client) attemptTimeout(call *Call) time.Duration {
+	if call.Timeout > 0 {
+		return call.Timeout
+	}
+	if call.Stream {
+		return 0
+	}
+	timeout := c.HTTPClient.Timeout
+	req := call.MessageRequest()
+	if req == nil || timeout == 0 || c.TimeoutPerOutputToken <= 0 {
+		return timeout
+	}
+	scaled := time.Duration(req.MaxTokens) * c.TimeoutPerOutputToken
+	if scaled > constants.MaxScaledTimeout {
+		scaled = constants.MaxScaledTimeout
+	}
+	if scaled > timeout {
+		return scaled
+	}
+	return timeout
+}
+
+// streamTimeouts returns the stream timeouts of call.
+func (c *Client) streamTimeouts(call *Call) StreamTimeouts {
+	if call.StreamTimeouts != nil {
+		return *call.StreamTimeouts
+	}
+	return c.StreamTimeouts
+}
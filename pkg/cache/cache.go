@@ -0,0 +1,177 @@
+// pkg/cache/cache.go
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
+)
+
+// Backend stores cached responses by key.
+type Backend interface {
+	// Get returns the response stored under key, or false if there is none
+	// or it has expired.
+	Get(key string) (*models.MessageResponse, bool, error)
+	// Set stores resp under key. A zero ttl means the entry never expires.
+	Set(key string, resp *models.MessageResponse, ttl time.Duration) error
+}
+
+// Cache serves repeated message requests from a Backend.
+type Cache struct {
+	Backend Backend
+	TTL     time.Duration
+}
+
+// New creates a Cache that stores entries in backend for ttl.
+func New(backend Backend, ttl time.Duration) *Cache {
+	return &Cache{Backend: backend, TTL: ttl}
+}
+
+// WithCache returns a ClientOption that serves CreateMessage and
+// StreamMessages calls from c.
+func WithCache(c *Cache) api.ClientOption {
+	return api.WithMiddleware(c.Middleware())
+}
+
+type bypassKey struct{}
+
+// Bypass returns a context for which cached responses are ignored. The fresh
+// response still replaces the cached one.
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+func bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+// Key returns the canonical cache key of req. It covers every field that
+// affects the response and ignores Stream and Metadata.
+func Key(req *models.MessageRequest) (string, error) {
+	canonical := *req
+	canonical.Stream = false
+	canonical.Metadata = nil
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Middleware returns middleware that answers message calls from the cache and
+// stores fresh responses. Cached responses are replayed as synthetic streams
+// for StreamMessages.
+func (c *Cache) Middleware() api.Middleware {
+	return func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+		req := call.MessageRequest()
+		if req == nil || call.Operation == "CountTokens" {
+			return next(ctx, call)
+		}
+		key, err := Key(req)
+		if err != nil {
+			return nil, err
+		}
+		if len(call.ExtraBody) > 0 {
+			extra, err := json.Marshal(call.ExtraBody)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode cache key: %w", err)
+			}
+			sum := sha256.Sum256(append([]byte(key), extra...))
+			key = hex.EncodeToString(sum[:])
+		}
+
+		if !bypassed(ctx) {
+			cached, ok, err := c.Backend.Get(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read cache: %w", err)
+			}
+			if ok {
+				if call.Stream {
+					stream, errs := Replay(cached)
+					return &api.Result{Stream: stream, StreamErrors: errs}, nil
+				}
+				return &api.Result{Value: cached}, nil
+			}
+		}
+
+		result, err := next(ctx, call)
+		if err != nil {
+			return result, err
+		}
+		if call.Stream && result.Stream != nil {
+			result.Stream, result.StreamErrors = c.record(key, result.Stream, result.StreamErrors)
+			return result, nil
+		}
+		if resp := result.MessageResponse(); resp != nil {
+			if err := c.Backend.Set(key, resp, c.TTL); err != nil {
+				return result, fmt.Errorf("failed to write cache: %w", err)
+			}
+		}
+		return result, nil
+	}
+}
+
+// record forwards stream chunks and errors and caches the assembled response
+// once the stream completes without error and with a finish reason for every
+// choice.
+func (c *Cache) record(key string, stream <-chan models.MessageResponse, errs <-chan error) (<-chan models.MessageResponse, <-chan error) {
+	output := make(chan models.MessageResponse)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		var assembled models.MessageResponse
+		failed := false
+		for stream != nil || errs != nil {
+			select {
+			case chunk, ok := <-stream:
+				if !ok {
+					stream = nil
+					continue
+				}
+				streams.AccumulateChunk(&assembled, chunk)
+				output <- chunk
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				failed = true
+				errCh <- err
+			}
+		}
+		if !failed && finished(&assembled) {
+			_ = c.Backend.Set(key, &assembled, c.TTL)
+		}
+	}()
+
+	return output, errCh
+}
+
+// finished reports whether every choice of resp has a finish reason.
+func finished(resp *models.MessageResponse) bool {
+	for _, choice := range resp.Choices {
+		if choice.FinishReason == "" {
+			return false
+		}
+	}
+	return len(resp.Choices) > 0
+}
+
+// Replay turns a complete response into a synthetic stream: one chunk with
+// the content of each choice followed by a final chunk carrying the finish
+// reasons and usage.
+func Replay(resp *models.MessageResponse) (<-chan models.MessageResponse, <-chan error) {
+	return streams.SyntheticStream(streams.MessageChunks(resp, 0))
+}
@@ -0,0 +1,83 @@
+// pkg/cache/file.go
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+type fileEntry struct {
+	Expires  time.Time               `json:"expires,omitempty"`
+	Response *models.MessageResponse `json:"response"`
+}
+
+// FileBackend stores each cached response as a JSON file in a directory, so
+// the cache survives restarts and can be shared between CI runs.
+type FileBackend struct {
+	dir string
+	now func() time.Time
+}
+
+// NewFileBackend creates a FileBackend in dir, creating the directory if needed.
+func NewFileBackend(dir string) (*FileBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileBackend{dir: dir, now: time.Now}, nil
+}
+
+func (b *FileBackend) path(key string) string {
+	return filepath.Join(b.dir, key+".json")
+}
+
+// Get implements Backend. Expired entries are removed.
+func (b *FileBackend) Get(key string) (*models.MessageResponse, bool, error) {
+	data, err := os.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var entry fileEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cache entry %s: %w", key, err)
+	}
+	if !entry.Expires.IsZero() && b.now().After(entry.Expires) {
+		_ = os.Remove(b.path(key))
+		return nil, false, nil
+	}
+	return entry.Response, true, nil
+}
+
+// Set implements Backend. The entry is written to a temporary file and renamed
+// so that concurrent readers never see a partial entry.
+func (b *FileBackend) Set(key string, resp *models.MessageResponse, ttl time.Duration) error {
+	entry := fileEntry{Response: resp}
+	if ttl > 0 {
+		entry.Expires = b.now().Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(b.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path(key))
+}
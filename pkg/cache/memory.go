@@ -0,0 +1,87 @@
+// pkg/cache/memory.go
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+type memoryEntry struct {
+	key     string
+	resp    *models.MessageResponse
+	expires time.Time
+}
+
+// MemoryBackend is an in-memory Backend that evicts the least recently used
+// entry once it holds Capacity entries.
+type MemoryBackend struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+// NewMemoryBackend creates a MemoryBackend holding at most capacity entries.
+// A capacity of zero or less means unbounded.
+func NewMemoryBackend(capacity int) *MemoryBackend {
+	return &MemoryBackend{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get implements Backend. It returns a copy of the stored response.
+func (b *MemoryBackend) Get(key string) (*models.MessageResponse, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elem, ok := b.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && b.now().After(entry.expires) {
+		b.order.Remove(elem)
+		delete(b.entries, key)
+		return nil, false, nil
+	}
+	b.order.MoveToFront(elem)
+	return entry.resp.Clone(), true, nil
+}
+
+// Set implements Backend. It stores a copy of resp.
+func (b *MemoryBackend) Set(key string, resp *models.MessageResponse, ttl time.Duration) error {
+	resp = resp.Clone()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = b.now().Add(ttl)
+	}
+	if elem, ok := b.entries[key]; ok {
+		elem.Value = &memoryEntry{key: key, resp: resp, expires: expires}
+		b.order.MoveToFront(elem)
+		return nil
+	}
+	b.entries[key] = b.order.PushFront(&memoryEntry{key: key, resp: resp, expires: expires})
+	for b.capacity > 0 && b.order.Len() > b.capacity {
+		oldest := b.order.Back()
+		b.order.Remove(oldest)
+		delete(b.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet evicted.
+func (b *MemoryBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.order.Len()
+}
@@ -0,0 +1,96 @@
+// pkg/conversation/boltstore.go
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var conversationsBucket = []byte("conversations")
+
+// BoltStore stores conversations in an embedded bbolt database file, which
+// suits a single process that needs durable history without a server.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the database at path. Only one process can
+// open the database at a time.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open conversation database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(conversationsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create conversation bucket: %w", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Save implements Store.
+func (s *BoltStore) Save(r *Record) error {
+	if r.ID == "" {
+		return fmt.Errorf("invalid conversation ID %q", r.ID)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(conversationsBucket).Put([]byte(r.ID), data)
+	})
+}
+
+// Load implements Store.
+func (s *BoltStore) Load(id string) (*Record, error) {
+	var r *Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(conversationsBucket).Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		var err error
+		r, err = decodeRecord(id, data)
+		return err
+	})
+	return r, err
+}
+
+// Delete implements Store.
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(conversationsBucket).Delete([]byte(id))
+	})
+}
+
+// List implements Store. It decodes every record in the database.
+func (s *BoltStore) List(filter Filter) ([]*Record, error) {
+	var records []*Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(conversationsBucket).ForEach(func(key, data []byte) error {
+			r, err := decodeRecord(string(key), data)
+			if err != nil {
+				return err
+			}
+			records = append(records, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return selectRecords(records, filter), nil
+}
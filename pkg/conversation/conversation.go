@@ -0,0 +1,471 @@
+// pkg/conversation/conversation.go
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
+)
+
+// Errors reported when a turn would make the conversation invalid.
+var (
+	ErrEmptyTurn         = errors.New("turn has no content")
+	ErrInvalidRole       = errors.New("turn role must be user or assistant")
+	ErrFirstTurn         = errors.New("first turn must be a user turn")
+	ErrRoleAlternation   = errors.New("turns must alternate between user and assistant")
+	ErrMissingToolResult = errors.New("tool_use without a tool_result")
+	ErrUnknownToolUse    = errors.New("tool_result without a matching tool_use")
+	ErrNoTurns           = errors.New("conversation has no turns")
+	// ErrConversationChanged is returned by Send and Stream when the turns
+	// changed while the request was in flight, e.g. by a concurrent Send.
+	// The reply is not recorded.
+	ErrConversationChanged = errors.New("conversation changed while the request was in flight")
+)
+
+// Turn is one message of a conversation. Assistant turns returned by Send or
+// Stream also record the response they came from.
+type Turn struct {
+	Message    models.Message      `json:"message"`
+	ResponseID string              `json:"response_id,omitempty"`
+	Model      string              `json:"model,omitempty"`
+	StopReason string              `json:"stop_reason,omitempty"`
+	Usage      models.MessageUsage `json:"usage"`
+	RequestID  string              `json:"request_id,omitempty"`
+	Latency    time.Duration       `json:"latency,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	// Pinned turns are never dropped or summarized by a ContextManager.
+	Pinned bool `json:"pinned,omitempty"`
+	// Response is the metadata of the HTTP response, if one was sent.
+	Response *api.ResponseMetadata `json:"-"`
+}
+
+// Conversation holds the system prompt, tools and turns of a chat and sends
+// them as one request. Turns are checked as they are added so that the
+// history always alternates between user and assistant and every tool_use is
+// answered by a tool_result in the following user turn.
+//
+// The methods of a Conversation are safe for concurrent use, but only one
+// request may be in flight at a time: a reply is recorded only if no turn was
+// added since its request was built. System, Tools and Params must not be
+// changed while a request is in flight.
+type Conversation struct {
+	// ID identifies the conversation in a Store. Save assigns one if empty.
+	ID string
+	// Metadata is stored with the conversation and can be searched with a
+	// Filter.
+	Metadata map[string]string
+
+	System string
+	Tools  []models.Tool
+	// Params holds the request fields other than Messages, System and
+	// Tools, such as MaxTokens and Temperature.
+	Params models.MessageRequest
+	// Context, if set, fits each request into its token budget. The turns
+	// themselves are kept in full.
+	Context *ContextManager
+
+	client    api.MessageClient
+	mu        sync.Mutex
+	turns     []Turn
+	createdAt time.Time
+}
+
+// New creates an empty Conversation sent with client using params.
+func New(client api.MessageClient, params models.MessageRequest) *Conversation {
+	params.Messages = nil
+	return &Conversation{client: client, Params: params, createdAt: time.Now()}
+}
+
+// AddUser appends a user turn with text content.
+func (c *Conversation) AddUser(text string) error {
+	return c.AddMessage(models.Message{Role: models.UserRole, Content: text})
+}
+
+// AddAssistant appends an assistant turn with text content. A trailing
+// assistant turn is sent as a prefill that the model continues.
+func (c *Conversation) AddAssistant(text string) error {
+	return c.AddMessage(models.Message{Role: models.AssistantRole, Content: text})
+}
+
+// AddMessage appends message as a new turn.
+func (c *Conversation) AddMessage(message models.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := checkTurn(c.turns, message); err != nil {
+		return err
+	}
+	c.turns = append(c.turns, Turn{Message: message, CreatedAt: time.Now()})
+	return nil
+}
+
+// AddToolResult answers the tool_use block toolUseID of the last assistant
+// turn. Results for the same assistant turn are collected in one user turn.
+func (c *Conversation) AddToolResult(toolUseID, content string, isError bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	block := models.ContentBlock{Type: models.ToolResultBlock, ToolUseID: toolUseID, Content: content, IsError: isError}
+	n := len(c.turns)
+	if n >= 2 && c.turns[n-1].Message.Role == models.UserRole && hasToolResults(c.turns[n-1].Message) {
+		last := c.turns[n-1].Message
+		last.Blocks = append(append([]models.ContentBlock(nil), last.Blocks...), block)
+		if err := checkTurn(c.turns[:n-1], last); err != nil {
+			return err
+		}
+		c.turns[n-1].Message = last
+		return nil
+	}
+
+	message := models.Message{Role: models.UserRole, Blocks: []models.ContentBlock{block}}
+	if err := checkTurn(c.turns, message); err != nil {
+		return err
+	}
+	c.turns = append(c.turns, Turn{Message: message, CreatedAt: time.Now()})
+	return nil
+}
+
+// PendingToolUses returns the tool_use blocks of the last assistant turn that
+// have no tool_result yet.
+func (c *Conversation) PendingToolUses() []models.ContentBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return pendingToolUses(c.turns)
+}
+
+// Pin marks the turn at index as pinned or not.
+func (c *Conversation) Pin(index int, pinned bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.turns) {
+		return fmt.Errorf("turn %d out of range", index)
+	}
+	c.turns[index].Pinned = pinned
+	return nil
+}
+
+// Turns returns a copy of the turns.
+func (c *Conversation) Turns() []Turn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Turn(nil), c.turns...)
+}
+
+// Messages returns the messages of the turns.
+func (c *Conversation) Messages() []models.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return messages(c.turns)
+}
+
+// Usage returns the usage summed over all turns.
+func (c *Conversation) Usage() models.MessageUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return sumUsage(c.turns)
+}
+
+func sumUsage(turns []Turn) models.MessageUsage {
+	var total models.MessageUsage
+	for _, turn := range turns {
+		total.PromptTokens += turn.Usage.PromptTokens
+		total.CompletionTokens += turn.Usage.CompletionTokens
+		total.TotalTokens += turn.Usage.TotalTokens
+		total.CacheCreationInputTokens += turn.Usage.CacheCreationInputTokens
+		total.CacheReadInputTokens += turn.Usage.CacheReadInputTokens
+	}
+	return total
+}
+
+// Request returns the request that Send would send, before it is fitted
+// into the context window.
+func (c *Conversation) Request() (*models.MessageRequest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.request()
+}
+
+// prepare returns the request to send, fitted by Context if set, and the
+// number of turns it was built from.
+func (c *Conversation) prepare(ctx context.Context) (*models.MessageRequest, int, error) {
+	c.mu.Lock()
+	req, err := c.request()
+	n := len(c.turns)
+	var pinned []int
+	for i, turn := range c.turns {
+		if turn.Pinned {
+			pinned = append(pinned, i)
+		}
+	}
+	c.mu.Unlock()
+	if err != nil || c.Context == nil {
+		return req, n, err
+	}
+	req, err = c.Context.Fit(ctx, req, pinned...)
+	return req, n, err
+}
+
+func (c *Conversation) request() (*models.MessageRequest, error) {
+	if len(c.turns) == 0 {
+		return nil, ErrNoTurns
+	}
+	if pending := pendingToolUses(c.turns); len(pending) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingToolResult, pending[0].ID)
+	}
+	req := c.Params
+	req.Messages = messages(c.turns)
+	req.System = c.System
+	req.Tools = append([]models.Tool(nil), c.Tools...)
+	return &req, nil
+}
+
+// Send sends the conversation with CreateMessage and appends the reply as an
+// assistant turn. A reply to an assistant prefill extends that turn. On
+// error the conversation is left unchanged, so Send can be retried.
+func (c *Conversation) Send(ctx context.Context, opts ...api.RequestOption) (*models.MessageResponse, error) {
+	req, n, err := c.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &api.ResponseMetadata{}
+	opts = append(opts, api.WithResponseMetadata(metadata))
+	resp, err := c.client.CreateMessage(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.record(n, resp, metadata); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// Stream sends the conversation with StreamMessages, forwarding its chunks,
+// and appends the assembled reply as an assistant turn once the stream ends
+// without error. Both channels must be drained.
+func (c *Conversation) Stream(ctx context.Context, opts ...api.RequestOption) (<-chan models.MessageResponse, <-chan error) {
+	req, n, err := c.prepare(ctx)
+	if err != nil {
+		return failedStream(err)
+	}
+	metadata := &api.ResponseMetadata{}
+	opts = append(opts, api.WithResponseMetadata(metadata))
+	stream, errs := c.client.StreamMessages(ctx, req, opts...)
+	return relay(stream, errs, func(assembled *models.MessageResponse) error {
+		return c.record(n, assembled, metadata)
+	})
+}
+
+// failedStream returns a stream that reports err and ends.
+func failedStream(err error) (<-chan models.MessageResponse, <-chan error) {
+	output := make(chan models.MessageResponse)
+	errCh := make(chan error)
+	go func() {
+		defer close(output)
+		defer close(errCh)
+		errCh <- err
+	}()
+	return output, errCh
+}
+
+// relay forwards stream and errs and, if the stream ends without error,
+// passes the assembled response to done, reporting its error.
+func relay(stream <-chan models.MessageResponse, errs <-chan error, done func(*models.MessageResponse) error) (<-chan models.MessageResponse, <-chan error) {
+	output := make(chan models.MessageResponse)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		var assembled models.MessageResponse
+		failed := false
+		for stream != nil || errs != nil {
+			select {
+			case chunk, ok := <-stream:
+				if !ok {
+					stream = nil
+					continue
+				}
+				streams.AccumulateChunk(&assembled, chunk)
+				output <- chunk
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				failed = true
+				errCh <- err
+			}
+		}
+		if failed {
+			return
+		}
+		if err := done(&assembled); err != nil {
+			errCh <- err
+		}
+	}()
+
+	return output, errCh
+}
+
+// record appends the first choice of resp as an assistant turn, provided the
+// conversation still has the n turns the request was built from.
+func (c *Conversation) record(n int, resp *models.MessageResponse, metadata *api.ResponseMetadata) error {
+	turn, err := replyTurn(resp, metadata)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.turns) != n {
+		return ErrConversationChanged
+	}
+	if n > 0 && c.turns[n-1].Message.Role == models.AssistantRole {
+		c.turns[n-1] = extendPrefill(c.turns[n-1], turn)
+		return nil
+	}
+	c.turns = append(c.turns, turn)
+	return nil
+}
+
+// replyTurn returns the assistant turn for the first choice of resp.
+func replyTurn(resp *models.MessageResponse, metadata *api.ResponseMetadata) (Turn, error) {
+	if len(resp.Choices) == 0 {
+		return Turn{}, fmt.Errorf("%w: response %q has no choices", ErrEmptyTurn, resp.ID)
+	}
+	turn := Turn{
+		Message:    resp.ToAssistantMessage(),
+		ResponseID: resp.ID,
+		Model:      resp.Model,
+		StopReason: string(resp.StopReason()),
+		Usage:      resp.Usage,
+		CreatedAt:  time.Now(),
+	}
+	if metadata.StatusCode != 0 {
+		turn.Response = metadata
+		turn.RequestID = metadata.RequestID
+		turn.Latency = metadata.Latency
+	}
+	return turn, nil
+}
+
+// extendPrefill returns reply with its message appended to the assistant
+// prefill it continues. The pin of the prefill is kept.
+func extendPrefill(prefill, reply Turn) Turn {
+	merged := models.MessageResponse{Choices: []models.MessageChoice{{Message: prefill.Message}}}
+	streams.AccumulateChunk(&merged, models.MessageResponse{Choices: []models.MessageChoice{{Message: reply.Message}}})
+	reply.Message = merged.Choices[0].Message
+	reply.Pinned = prefill.Pinned
+	return reply
+}
+
+// checkTurn reports whether message may follow turns.
+func checkTurn(turns []Turn, message models.Message) error {
+	if message.Role != models.UserRole && message.Role != models.AssistantRole {
+		return fmt.Errorf("%w: got %q", ErrInvalidRole, message.Role)
+	}
+	if message.Content == "" && len(message.Blocks) == 0 {
+		return ErrEmptyTurn
+	}
+	if len(turns) == 0 {
+		if message.Role != models.UserRole {
+			return ErrFirstTurn
+		}
+		return checkToolResults(nil, message)
+	}
+	last := turns[len(turns)-1].Message
+	if last.Role == message.Role {
+		return fmt.Errorf("%w: two %s turns in a row", ErrRoleAlternation, message.Role)
+	}
+	if message.Role == models.AssistantRole {
+		if pending := pendingToolUses(turns); len(pending) > 0 {
+			return fmt.Errorf("%w: %s", ErrMissingToolResult, pending[0].ID)
+		}
+		return nil
+	}
+	uses := toolUses(last)
+	if err := checkToolResults(uses, message); err != nil {
+		return err
+	}
+	if len(uses) > 0 && !hasToolResults(message) {
+		return fmt.Errorf("%w: %s", ErrMissingToolResult, uses[0].ID)
+	}
+	return nil
+}
+
+// checkToolResults reports a tool_result in message that answers none of uses.
+func checkToolResults(uses []models.ContentBlock, message models.Message) error {
+	for _, block := range message.Blocks {
+		if block.Type != models.ToolResultBlock {
+			continue
+		}
+		found := false
+		for _, use := range uses {
+			found = found || use.ID == block.ToolUseID
+		}
+		if !found {
+			return fmt.Errorf("%w: %s", ErrUnknownToolUse, block.ToolUseID)
+		}
+	}
+	return nil
+}
+
+// pendingToolUses returns the tool_use blocks of the last assistant turn that
+// the turn after it, if any, does not answer.
+func pendingToolUses(turns []Turn) []models.ContentBlock {
+	i := len(turns) - 1
+	if i >= 0 && turns[i].Message.Role == models.UserRole {
+		i--
+	}
+	if i < 0 || turns[i].Message.Role != models.AssistantRole {
+		return nil
+	}
+	answered := make(map[string]bool)
+	if i+1 < len(turns) {
+		for _, block := range turns[i+1].Message.Blocks {
+			if block.Type == models.ToolResultBlock {
+				answered[block.ToolUseID] = true
+			}
+		}
+	}
+	var pending []models.ContentBlock
+	for _, use := range toolUses(turns[i].Message) {
+		if !answered[use.ID] {
+			pending = append(pending, use)
+		}
+	}
+	return pending
+}
+
+func toolUses(message models.Message) []models.ContentBlock {
+	var uses []models.ContentBlock
+	for _, block := range message.Blocks {
+		if block.Type == models.ToolUseBlock {
+			uses = append(uses, block)
+		}
+	}
+	return uses
+}
+
+func hasToolResults(message models.Message) bool {
+	for _, block := range message.Blocks {
+		if block.Type == models.ToolResultBlock {
+			return true
+		}
+	}
+	return false
+}
+
+func messages(turns []Turn) []models.Message {
+	messages := make([]models.Message, len(turns))
+	for i, turn := range turns {
+		messages[i] = turn.Message
+	}
+	return messages
+}
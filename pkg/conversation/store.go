@@ -0,0 +1,186 @@
+// pkg/conversation/store.go
+package conversation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// ErrNotFound is returned by a Store for an unknown conversation ID.
+var ErrNotFound = errors.New("conversation not found")
+
+// Record is the stored form of a conversation: its request settings and
+// every turn with content blocks, usage and response details.
+type Record struct {
+	ID        string                `json:"id"`
+	Metadata  map[string]string     `json:"metadata,omitempty"`
+	System    string                `json:"system,omitempty"`
+	Tools     []models.Tool         `json:"tools,omitempty"`
+	Params    models.MessageRequest `json:"params"`
+	Turns     []Turn                `json:"turns"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	// Tree holds every branch of a record saved from a Tree. Turns then
+	// hold the active branch.
+	Tree *TreeRecord `json:"tree,omitempty"`
+}
+
+// Request returns a request with the system prompt, tools and turns of r,
+// ready for CreateMessage.
+func (r *Record) Request() *models.MessageRequest {
+	req := r.Params
+	req.Messages = messages(r.Turns)
+	req.System = r.System
+	req.Tools = append([]models.Tool(nil), r.Tools...)
+	return &req
+}
+
+// Usage returns the usage summed over all turns of r.
+func (r *Record) Usage() models.MessageUsage {
+	return sumUsage(r.Turns)
+}
+
+// Filter selects records in Store.List. Zero fields match everything.
+type Filter struct {
+	// Metadata must all be present in a record with the same values.
+	Metadata map[string]string
+	// Text must occur, ignoring case, in the text of a turn on any branch.
+	Text string
+	// Limit caps the number of records returned.
+	Limit int
+}
+
+// Match reports whether r is selected by f.
+func (f Filter) Match(r *Record) bool {
+	for key, value := range f.Metadata {
+		if got, ok := r.Metadata[key]; !ok || got != value {
+			return false
+		}
+	}
+	if f.Text == "" {
+		return true
+	}
+	text := strings.ToLower(f.Text)
+	turns := r.Turns
+	if r.Tree != nil {
+		turns = nil
+		for _, node := range r.Tree.Nodes {
+			turns = append(turns, node.Turn)
+		}
+	}
+	for _, turn := range turns {
+		if strings.Contains(strings.ToLower(turn.Message.Content), text) {
+			return true
+		}
+		for _, block := range turn.Message.Blocks {
+			if strings.Contains(strings.ToLower(block.Text), text) || strings.Contains(strings.ToLower(block.Content), text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Store persists conversations so that they survive restarts.
+type Store interface {
+	// Save stores r under r.ID, replacing any previous version.
+	Save(r *Record) error
+	// Load returns the record with id, or ErrNotFound.
+	Load(id string) (*Record, error)
+	// Delete removes the record with id. Deleting an unknown ID is not an error.
+	Delete(id string) error
+	// List returns the records selected by filter, most recently updated first.
+	List(filter Filter) ([]*Record, error)
+}
+
+// selectRecords filters and sorts records for List.
+func selectRecords(records []*Record, filter Filter) []*Record {
+	var selected []*Record
+	for _, r := range records {
+		if filter.Match(r) {
+			selected = append(selected, r)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].UpdatedAt.After(selected[j].UpdatedAt)
+	})
+	if filter.Limit > 0 && len(selected) > filter.Limit {
+		selected = selected[:filter.Limit]
+	}
+	return selected
+}
+
+// newID returns a random ID with prefix.
+func newID(prefix string) string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return prefix + hex.EncodeToString(b[:])
+}
+
+// Record returns a snapshot of the conversation for a Store.
+func (c *Conversation) Record() *Record {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := &Record{
+		ID:        c.ID,
+		System:    c.System,
+		Tools:     append([]models.Tool(nil), c.Tools...),
+		Params:    c.Params,
+		Turns:     append([]Turn(nil), c.turns...),
+		CreatedAt: c.createdAt,
+		UpdatedAt: time.Now(),
+		Metadata:  copyMetadata(c.Metadata),
+	}
+	return r
+}
+
+func copyMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
+
+// Save stores the conversation in store, assigning it an ID if it has none.
+func (c *Conversation) Save(store Store) error {
+	c.mu.Lock()
+	if c.ID == "" {
+		c.ID = newID("conv_")
+	}
+	c.mu.Unlock()
+	return store.Save(c.Record())
+}
+
+// Restore creates a Conversation from a stored record, sent with client. A
+// record saved from a Tree restores its active branch; saving the
+// Conversation back drops the other branches.
+func Restore(client api.MessageClient, r *Record) *Conversation {
+	c := New(client, r.Params)
+	c.ID = r.ID
+	c.Metadata = r.Metadata
+	c.System = r.System
+	c.Tools = r.Tools
+	c.turns = append([]Turn(nil), r.Turns...)
+	c.createdAt = r.CreatedAt
+	return c
+}
+
+// Load restores the conversation with id from store, sent with client.
+func Load(client api.MessageClient, store Store, id string) (*Conversation, error) {
+	r, err := store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	return Restore(client, r), nil
+}
@@ -0,0 +1,476 @@
+// pkg/conversation/tree.go
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// Node is a turn in a Tree. A node with an empty Parent starts a branch at
+// the root.
+type Node struct {
+	ID     string `json:"id"`
+	Parent string `json:"parent,omitempty"`
+	Turn   Turn   `json:"turn"`
+}
+
+// TreeRecord is the stored form of a Tree. Nodes are kept in the order they
+// were added.
+type TreeRecord struct {
+	Nodes  []Node `json:"nodes"`
+	Active string `json:"active,omitempty"`
+}
+
+// Comparison describes where two branches of a Tree diverge.
+type Comparison struct {
+	// ForkPoint is the last node the branches share, or empty if they
+	// differ from the first turn.
+	ForkPoint string
+	Common    []Turn
+	A, B      []Turn
+}
+
+// Tree is a conversation in which any turn can be edited or regenerated,
+// forking a new branch instead of losing the old one. One branch is active:
+// it ends at the active node, and Send, Request and Record use the path from
+// the root to it. Turns are checked against the path they extend with the
+// same rules as a Conversation.
+//
+// The methods of a Tree are safe for concurrent use.
+type Tree struct {
+	ID       string
+	Metadata map[string]string
+	System   string
+	Tools    []models.Tool
+	Params   models.MessageRequest
+	Context  *ContextManager
+
+	client    api.MessageClient
+	mu        sync.Mutex
+	nodes     map[string]*Node
+	order     []string
+	children  map[string][]string
+	active    string
+	createdAt time.Time
+}
+
+// NewTree creates an empty Tree sent with client using params.
+func NewTree(client api.MessageClient, params models.MessageRequest) *Tree {
+	params.Messages = nil
+	return &Tree{
+		client:    client,
+		Params:    params,
+		nodes:     make(map[string]*Node),
+		children:  make(map[string][]string),
+		createdAt: time.Now(),
+	}
+}
+
+// Active returns the ID of the active node, or empty for an empty tree.
+func (t *Tree) Active() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active
+}
+
+// Node returns a copy of the node with id.
+func (t *Tree) Node(id string) (Node, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.nodes[id]
+	if !ok {
+		return Node{}, false
+	}
+	return *node, true
+}
+
+// Children returns the IDs of the nodes that follow id, oldest first. An
+// empty id returns the first turns of every branch.
+func (t *Tree) Children(id string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.children[id]...)
+}
+
+// Siblings returns the IDs of id and the alternatives to it: the nodes that
+// share its parent, oldest first.
+func (t *Tree) Siblings(id string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.nodes[id]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), t.children[node.Parent]...)
+}
+
+// Leaves returns the last node of every branch, oldest first.
+func (t *Tree) Leaves() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var leaves []string
+	for _, id := range t.order {
+		if len(t.children[id]) == 0 {
+			leaves = append(leaves, id)
+		}
+	}
+	return leaves
+}
+
+// Path returns the turns from the root to id.
+func (t *Tree) Path(id string) ([]Turn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.nodes[id]; !ok {
+		return nil, fmt.Errorf("%w: node %s", ErrNotFound, id)
+	}
+	return t.path(id), nil
+}
+
+// Turns returns the turns of the active branch.
+func (t *Tree) Turns() []Turn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.path(t.active)
+}
+
+func (t *Tree) path(id string) []Turn {
+	var turns []Turn
+	for id != "" {
+		node := t.nodes[id]
+		turns = append(turns, node.Turn)
+		id = node.Parent
+	}
+	for i, j := 0, len(turns)-1; i < j; i, j = i+1, j-1 {
+		turns[i], turns[j] = turns[j], turns[i]
+	}
+	return turns
+}
+
+// Select makes the branch through id active. The branch continues from id
+// to its most recently added leaf.
+func (t *Tree) Select(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.nodes[id]; !ok {
+		return fmt.Errorf("%w: node %s", ErrNotFound, id)
+	}
+	for children := t.children[id]; len(children) > 0; children = t.children[id] {
+		id = children[len(children)-1]
+	}
+	t.active = id
+	return nil
+}
+
+// AddUser appends a user turn to the active branch.
+func (t *Tree) AddUser(text string) (string, error) {
+	return t.AddMessage(models.Message{Role: models.UserRole, Content: text})
+}
+
+// AddAssistant appends an assistant turn to the active branch.
+func (t *Tree) AddAssistant(text string) (string, error) {
+	return t.AddMessage(models.Message{Role: models.AssistantRole, Content: text})
+}
+
+// AddMessage appends message to the active branch and returns the ID of the
+// new node, which becomes active.
+func (t *Tree) AddMessage(message models.Message) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.add(t.active, Turn{Message: message, CreatedAt: time.Now()})
+}
+
+// AddToolResult answers the tool_use block toolUseID of the last assistant
+// turn of the active branch, collecting results in one user turn.
+func (t *Tree) AddToolResult(toolUseID, content string, isError bool) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	block := models.ContentBlock{Type: models.ToolResultBlock, ToolUseID: toolUseID, Content: content, IsError: isError}
+	if node, ok := t.nodes[t.active]; ok && node.Turn.Message.Role == models.UserRole && hasToolResults(node.Turn.Message) {
+		message := node.Turn.Message
+		message.Blocks = append(append([]models.ContentBlock(nil), message.Blocks...), block)
+		if err := checkTurn(t.path(node.Parent), message); err != nil {
+			return "", err
+		}
+		node.Turn.Message = message
+		return node.ID, nil
+	}
+	message := models.Message{Role: models.UserRole, Blocks: []models.ContentBlock{block}}
+	return t.add(t.active, Turn{Message: message, CreatedAt: time.Now()})
+}
+
+// Edit forks the turn id: message is added as an alternative to it, after
+// the same parent, and the new branch becomes active. The original turn and
+// everything after it are kept.
+func (t *Tree) Edit(id string, message models.Message) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.nodes[id]
+	if !ok {
+		return "", fmt.Errorf("%w: node %s", ErrNotFound, id)
+	}
+	return t.add(node.Parent, Turn{Message: message, CreatedAt: time.Now()})
+}
+
+// add appends turn after parent and makes it active.
+func (t *Tree) add(parent string, turn Turn) (string, error) {
+	if err := checkTurn(t.path(parent), turn.Message); err != nil {
+		return "", err
+	}
+	node := &Node{ID: newID("turn_"), Parent: parent, Turn: turn}
+	t.insert(node)
+	t.active = node.ID
+	return node.ID, nil
+}
+
+func (t *Tree) insert(node *Node) {
+	t.nodes[node.ID] = node
+	t.order = append(t.order, node.ID)
+	t.children[node.Parent] = append(t.children[node.Parent], node.ID)
+}
+
+// Request returns the request for the active branch.
+func (t *Tree) Request() (*models.MessageRequest, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.request(t.active)
+}
+
+func (t *Tree) request(id string) (*models.MessageRequest, error) {
+	turns := t.path(id)
+	if len(turns) == 0 {
+		return nil, ErrNoTurns
+	}
+	if pending := pendingToolUses(turns); len(pending) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingToolResult, pending[0].ID)
+	}
+	req := t.Params
+	req.Messages = messages(turns)
+	req.System = t.System
+	req.Tools = append([]models.Tool(nil), t.Tools...)
+	return &req, nil
+}
+
+// Send sends the active branch with CreateMessage and appends the reply to
+// it. A reply to an assistant prefill forks the prefill into a new node that
+// holds the completed turn.
+func (t *Tree) Send(ctx context.Context, opts ...api.RequestOption) (*models.MessageResponse, error) {
+	t.mu.Lock()
+	parent := t.active
+	t.mu.Unlock()
+	return t.generate(ctx, parent, opts)
+}
+
+// Regenerate asks for a new reply in place of the assistant turn id. The
+// reply is added as an alternative to id and becomes active.
+func (t *Tree) Regenerate(ctx context.Context, id string, opts ...api.RequestOption) (*models.MessageResponse, error) {
+	t.mu.Lock()
+	node, ok := t.nodes[id]
+	var role models.MessageRoleType
+	var parent string
+	if ok {
+		role, parent = node.Turn.Message.Role, node.Parent
+	}
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: node %s", ErrNotFound, id)
+	}
+	if role != models.AssistantRole {
+		return nil, fmt.Errorf("cannot regenerate %s turn %s", role, id)
+	}
+	return t.generate(ctx, parent, opts)
+}
+
+// generate sends the path to parent and adds the reply after it.
+func (t *Tree) generate(ctx context.Context, parent string, opts []api.RequestOption) (*models.MessageResponse, error) {
+	req, err := t.prepare(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &api.ResponseMetadata{}
+	opts = append(opts, api.WithResponseMetadata(metadata))
+	resp, err := t.client.CreateMessage(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.record(parent, resp, metadata); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// Stream sends the active branch with StreamMessages and appends the
+// assembled reply once the stream ends without error. Both channels must be
+// drained.
+func (t *Tree) Stream(ctx context.Context, opts ...api.RequestOption) (<-chan models.MessageResponse, <-chan error) {
+	t.mu.Lock()
+	parent := t.active
+	t.mu.Unlock()
+	req, err := t.prepare(ctx, parent)
+	if err != nil {
+		return failedStream(err)
+	}
+	metadata := &api.ResponseMetadata{}
+	opts = append(opts, api.WithResponseMetadata(metadata))
+	stream, errs := t.client.StreamMessages(ctx, req, opts...)
+	return relay(stream, errs, func(assembled *models.MessageResponse) error {
+		return t.record(parent, assembled, metadata)
+	})
+}
+
+// prepare returns the request for the path to id, fitted by Context if set.
+func (t *Tree) prepare(ctx context.Context, id string) (*models.MessageRequest, error) {
+	t.mu.Lock()
+	req, err := t.request(id)
+	var pinned []int
+	for i, turn := range t.path(id) {
+		if turn.Pinned {
+			pinned = append(pinned, i)
+		}
+	}
+	t.mu.Unlock()
+	if err != nil || t.Context == nil {
+		return req, err
+	}
+	return t.Context.Fit(ctx, req, pinned...)
+}
+
+// record adds the reply in resp after parent and makes it active.
+func (t *Tree) record(parent string, resp *models.MessageResponse, metadata *api.ResponseMetadata) error {
+	turn, err := replyTurn(resp, metadata)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if prefill, ok := t.nodes[parent]; ok && prefill.Turn.Message.Role == models.AssistantRole {
+		node := &Node{ID: newID("turn_"), Parent: prefill.Parent, Turn: extendPrefill(prefill.Turn, turn)}
+		t.insert(node)
+		t.active = node.ID
+		return nil
+	}
+	node := &Node{ID: newID("turn_"), Parent: parent, Turn: turn}
+	t.insert(node)
+	t.active = node.ID
+	return nil
+}
+
+// Compare returns the turns shared by the branches ending at a and b and the
+// turns where they differ.
+func (t *Tree) Compare(a, b string) (*Comparison, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range []string{a, b} {
+		if _, ok := t.nodes[id]; !ok {
+			return nil, fmt.Errorf("%w: node %s", ErrNotFound, id)
+		}
+	}
+	pathA, pathB := t.ids(a), t.ids(b)
+	n := 0
+	for n < len(pathA) && n < len(pathB) && pathA[n] == pathB[n] {
+		n++
+	}
+	comparison := &Comparison{}
+	if n > 0 {
+		comparison.ForkPoint = pathA[n-1]
+	}
+	turnsA, turnsB := t.path(a), t.path(b)
+	comparison.Common = turnsA[:n]
+	comparison.A = turnsA[n:]
+	comparison.B = turnsB[n:]
+	return comparison, nil
+}
+
+// ids returns the node IDs from the root to id.
+func (t *Tree) ids(id string) []string {
+	var ids []string
+	for ; id != ""; id = t.nodes[id].Parent {
+		ids = append([]string{id}, ids...)
+	}
+	return ids
+}
+
+// Record returns a snapshot of the tree for a Store. Its Turns hold the
+// active branch, so it can also be restored as a linear Conversation.
+func (t *Tree) Record() *Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := &Record{
+		ID:        t.ID,
+		Metadata:  copyMetadata(t.Metadata),
+		System:    t.System,
+		Tools:     append([]models.Tool(nil), t.Tools...),
+		Params:    t.Params,
+		Turns:     t.path(t.active),
+		CreatedAt: t.createdAt,
+		UpdatedAt: time.Now(),
+		Tree:      &TreeRecord{Active: t.active},
+	}
+	for _, id := range t.order {
+		r.Tree.Nodes = append(r.Tree.Nodes, *t.nodes[id])
+	}
+	return r
+}
+
+// Save stores the tree in store, assigning it an ID if it has none.
+func (t *Tree) Save(store Store) error {
+	t.mu.Lock()
+	if t.ID == "" {
+		t.ID = newID("conv_")
+	}
+	t.mu.Unlock()
+	return store.Save(t.Record())
+}
+
+// RestoreTree creates a Tree from a stored record, sent with client. A
+// record saved from a linear Conversation becomes a tree with one branch.
+func RestoreTree(client api.MessageClient, r *Record) (*Tree, error) {
+	t := NewTree(client, r.Params)
+	t.ID = r.ID
+	t.Metadata = r.Metadata
+	t.System = r.System
+	t.Tools = r.Tools
+	t.createdAt = r.CreatedAt
+
+	if r.Tree == nil {
+		parent := ""
+		for _, turn := range r.Turns {
+			node := &Node{ID: newID("turn_"), Parent: parent, Turn: turn}
+			t.insert(node)
+			parent = node.ID
+		}
+		t.active = parent
+		return t, nil
+	}
+
+	for _, node := range r.Tree.Nodes {
+		node := node
+		if _, ok := t.nodes[node.ID]; ok {
+			return nil, fmt.Errorf("duplicate node %s in conversation %s", node.ID, r.ID)
+		}
+		if _, ok := t.nodes[node.Parent]; node.Parent != "" && !ok {
+			return nil, fmt.Errorf("node %s of conversation %s has unknown parent %s", node.ID, r.ID, node.Parent)
+		}
+		t.insert(&node)
+	}
+	if _, ok := t.nodes[r.Tree.Active]; r.Tree.Active != "" && !ok {
+		return nil, fmt.Errorf("unknown active node %s in conversation %s", r.Tree.Active, r.ID)
+	}
+	t.active = r.Tree.Active
+	return t, nil
+}
+
+// LoadTree restores the tree with id from store, sent with client.
+func LoadTree(client api.MessageClient, store Store, id string) (*Tree, error) {
+	r, err := store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	return RestoreTree(client, r)
+}
@@ -0,0 +1,377 @@
+// pkg/conversation/window.go
+package conversation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/tokens"
+)
+
+// ErrContextTooLarge is returned when no strategy can shrink a request any
+// further and it still exceeds the token budget.
+var ErrContextTooLarge = errors.New("request exceeds the context window")
+
+// TokenCounter measures the input tokens of a request.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, req *models.MessageRequest) (int, error)
+}
+
+// TokenCounterFunc adapts a function to a TokenCounter.
+type TokenCounterFunc func(ctx context.Context, req *models.MessageRequest) (int, error)
+
+// CountTokens implements TokenCounter.
+func (f TokenCounterFunc) CountTokens(ctx context.Context, req *models.MessageRequest) (int, error) {
+	return f(ctx, req)
+}
+
+// APICounter counts tokens with the count-tokens endpoint of client.
+func APICounter(client api.MessageClient) TokenCounter {
+	return TokenCounterFunc(func(ctx context.Context, req *models.MessageRequest) (int, error) {
+		resp, err := client.CountTokens(ctx, req)
+		if err != nil {
+			return 0, err
+		}
+		return resp.InputTokens, nil
+	})
+}
+
+// EstimateCounter counts tokens locally with tokens.DefaultEstimator. It is
+// fast and free but only approximate, so leave headroom in the budget.
+var EstimateCounter TokenCounter = TokenCounterFunc(func(ctx context.Context, req *models.MessageRequest) (int, error) {
+	return tokens.DefaultEstimator.Estimate(req), nil
+})
+
+// Window is the history a Strategy shrinks. Pinned[i] reports whether
+// Messages[i] must not be dropped, summarized or cleared.
+type Window struct {
+	Messages []models.Message
+	Pinned   []bool
+}
+
+// exchanges splits the window into exchanges: a user turn that is not only
+// tool results, followed by every turn up to the next such user turn. An
+// exchange always contains both halves of its tool_use/tool_result pairs,
+// and dropping whole exchanges keeps the roles alternating.
+func (w *Window) exchanges() [][2]int {
+	var spans [][2]int
+	for i, message := range w.Messages {
+		starts := message.Role == models.UserRole && !onlyToolResults(message)
+		if starts || len(spans) == 0 {
+			spans = append(spans, [2]int{i, i + 1})
+			continue
+		}
+		spans[len(spans)-1][1] = i + 1
+	}
+	return spans
+}
+
+func (w *Window) pinned(span [2]int) bool {
+	for i := span[0]; i < span[1]; i++ {
+		if i < len(w.Pinned) && w.Pinned[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// remove deletes the messages in span, replacing them with replacement.
+func (w *Window) remove(span [2]int, replacement ...models.Message) {
+	messages := append([]models.Message(nil), w.Messages[:span[0]]...)
+	messages = append(messages, replacement...)
+	messages = append(messages, w.Messages[span[1]:]...)
+	pinned := make([]bool, len(messages))
+	for i := range pinned {
+		switch {
+		case i < span[0]:
+			pinned[i] = i < len(w.Pinned) && w.Pinned[i]
+		case i >= span[0]+len(replacement):
+			j := i - len(replacement) + span[1] - span[0]
+			pinned[i] = j < len(w.Pinned) && w.Pinned[j]
+		}
+	}
+	w.Messages, w.Pinned = messages, pinned
+}
+
+func onlyToolResults(message models.Message) bool {
+	if len(message.Blocks) == 0 {
+		return false
+	}
+	for _, block := range message.Blocks {
+		if block.Type != models.ToolResultBlock {
+			return false
+		}
+	}
+	return true
+}
+
+// Strategy shrinks a window that is excess tokens over budget. It reports
+// whether it changed the window; a strategy that can do no more returns
+// false so that the next one is tried.
+type Strategy interface {
+	Shrink(ctx context.Context, w *Window, excess int) (bool, error)
+}
+
+// ContextManager fits requests into a token budget by applying its
+// strategies in order until the request is small enough. The last exchange,
+// pinned turns and tool_use/tool_result pairs are always kept.
+type ContextManager struct {
+	Counter TokenCounter
+	// MaxTokens is the budget for input tokens, e.g. the context window of
+	// the model less the tokens reserved for output.
+	MaxTokens  int
+	Strategies []Strategy
+}
+
+// NewContextManager creates a ContextManager that drops the oldest exchanges
+// when no strategies are given.
+func NewContextManager(counter TokenCounter, maxTokens int, strategies ...Strategy) *ContextManager {
+	if len(strategies) == 0 {
+		strategies = []Strategy{DropOldest{}}
+	}
+	return &ContextManager{Counter: counter, MaxTokens: maxTokens, Strategies: strategies}
+}
+
+// Fit returns a copy of req that fits the budget. pinned lists the indexes
+// of messages that must not be dropped, summarized or cleared.
+//
+// The Counter is called once for req and once more to confirm the fitted
+// request. In between, the tokens saved by each change are estimated with
+// tokens.DefaultEstimator, so that a Counter backed by the API is not called
+// for every dropped exchange. If the confirmed count is still over budget,
+// shrinking resumes from that count.
+func (m *ContextManager) Fit(ctx context.Context, req *models.MessageRequest, pinned ...int) (*models.MessageRequest, error) {
+	fitted := *req
+	window := &Window{Messages: append([]models.Message(nil), req.Messages...), Pinned: make([]bool, len(req.Messages))}
+	for _, i := range pinned {
+		if i >= 0 && i < len(window.Pinned) {
+			window.Pinned[i] = true
+		}
+	}
+
+	fitted.Messages = window.Messages
+	counted, err := m.Counter.CountTokens(ctx, &fitted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	estimated := tokens.DefaultEstimator.Estimate(&fitted)
+	size, verified := counted, true
+	for {
+		excess := size - m.MaxTokens
+		if excess <= 0 && verified {
+			return &fitted, nil
+		}
+		if excess <= 0 {
+			if counted, err = m.Counter.CountTokens(ctx, &fitted); err != nil {
+				return nil, fmt.Errorf("failed to count tokens: %w", err)
+			}
+			estimated = tokens.DefaultEstimator.Estimate(&fitted)
+			size, verified = counted, true
+			continue
+		}
+
+		shrunk := false
+		for _, strategy := range m.Strategies {
+			if shrunk, err = strategy.Shrink(ctx, window, excess); err != nil {
+				return nil, err
+			}
+			if shrunk {
+				break
+			}
+		}
+		if !shrunk {
+			return nil, fmt.Errorf("%w: %d tokens over a budget of %d", ErrContextTooLarge, excess, m.MaxTokens)
+		}
+		fitted.Messages = window.Messages
+		size = counted + tokens.DefaultEstimator.Estimate(&fitted) - estimated
+		verified = false
+	}
+}
+
+// DropOldest drops the oldest exchange that has no pinned turn.
+type DropOldest struct{}
+
+// Shrink implements Strategy.
+func (DropOldest) Shrink(ctx context.Context, w *Window, excess int) (bool, error) {
+	spans := w.exchanges()
+	for _, span := range spans[:max(len(spans)-1, 0)] {
+		if !w.pinned(span) {
+			w.remove(span)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ClearToolResults replaces the content of old tool results with a
+// placeholder, keeping the blocks so that every tool_use stays answered.
+type ClearToolResults struct {
+	// Keep is the number of most recent tool results left intact.
+	Keep int
+	// Placeholder replaces the cleared content. It defaults to
+	// "[tool result cleared]".
+	Placeholder string
+}
+
+// Shrink implements Strategy. It clears every old tool result at once.
+func (s ClearToolResults) Shrink(ctx context.Context, w *Window, excess int) (bool, error) {
+	placeholder := s.Placeholder
+	if placeholder == "" {
+		placeholder = "[tool result cleared]"
+	}
+
+	var results [][2]int
+	for i, message := range w.Messages {
+		for j, block := range message.Blocks {
+			if block.Type == models.ToolResultBlock {
+				results = append(results, [2]int{i, j})
+			}
+		}
+	}
+
+	cleared := false
+	for _, at := range results[:max(len(results)-s.Keep, 0)] {
+		i, j := at[0], at[1]
+		if (i < len(w.Pinned) && w.Pinned[i]) || w.Messages[i].Blocks[j].Content == placeholder {
+			continue
+		}
+		blocks := append([]models.ContentBlock(nil), w.Messages[i].Blocks...)
+		blocks[j].Content = placeholder
+		w.Messages[i].Blocks = blocks
+		cleared = true
+	}
+	return cleared, nil
+}
+
+// DefaultSummaryPrompt asks for a summary of the transcript that follows it.
+const DefaultSummaryPrompt = "Summarize the following conversation so that it can be continued without it. " +
+	"Keep names, decisions, open questions and any facts the assistant will need.\n\n"
+
+// Summarize replaces the oldest unpinned exchanges with a summary written by
+// a separate, usually cheaper, model. The summary is prepended to the first
+// user turn that is kept. Summaries are cached by transcript, so refitting
+// the same history does not summarize it again.
+type Summarize struct {
+	Client api.MessageClient
+	// Params configures the summary request, e.g. a smaller model and
+	// MaxTokens for the summary.
+	Params models.MessageRequest
+	// Prompt defaults to DefaultSummaryPrompt.
+	Prompt string
+	// KeepRecent is the number of most recent exchanges never summarized.
+	// The last exchange is always kept.
+	KeepRecent int
+
+	mu        sync.Mutex
+	summaries map[string]string
+}
+
+// Shrink implements Strategy.
+func (s *Summarize) Shrink(ctx context.Context, w *Window, excess int) (bool, error) {
+	spans := w.exchanges()
+	keep := max(s.KeepRecent, 1)
+	if len(spans) <= keep {
+		return false, nil
+	}
+	candidates := spans[:len(spans)-keep]
+
+	// Summarize the first run of unpinned exchanges.
+	start := 0
+	for start < len(candidates) && w.pinned(candidates[start]) {
+		start++
+	}
+	end := start
+	for end < len(candidates) && !w.pinned(candidates[end]) {
+		end++
+	}
+	if end == start {
+		return false, nil
+	}
+	span := [2]int{candidates[start][0], candidates[end-1][1]}
+
+	transcript := renderTranscript(w.Messages[span[0]:span[1]])
+	summary, err := s.summarize(ctx, transcript)
+	if err != nil {
+		return false, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+
+	next := w.Messages[span[1]]
+	nextPinned := span[1] < len(w.Pinned) && w.Pinned[span[1]]
+	text := "Summary of the earlier conversation:\n" + summary + "\n\n"
+	if len(next.Blocks) == 0 {
+		next.Content = text + next.Content
+	} else {
+		next.Blocks = append([]models.ContentBlock{{Type: models.TextBlock, Text: text}}, next.Blocks...)
+		next.Content = text + next.Content
+	}
+	w.remove([2]int{span[0], span[1] + 1}, next)
+	w.Pinned[span[0]] = nextPinned
+	return true, nil
+}
+
+func (s *Summarize) summarize(ctx context.Context, transcript string) (string, error) {
+	sum := sha256.Sum256([]byte(transcript))
+	key := hex.EncodeToString(sum[:])
+	s.mu.Lock()
+	summary, ok := s.summaries[key]
+	s.mu.Unlock()
+	if ok {
+		return summary, nil
+	}
+
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+	req := s.Params
+	req.Messages = []models.Message{{Role: models.UserRole, Content: prompt + transcript}}
+	resp, err := s.Client.CreateMessage(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(resp.Text())
+	if summary == "" {
+		return "", fmt.Errorf("empty summary in response %q", resp.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.summaries == nil {
+		s.summaries = make(map[string]string)
+	}
+	s.summaries[key] = summary
+	return summary, nil
+}
+
+// renderTranscript writes messages as plain text for summarization.
+func renderTranscript(messages []models.Message) string {
+	var b strings.Builder
+	for _, message := range messages {
+		name := "User"
+		if message.Role == models.AssistantRole {
+			name = "Assistant"
+		}
+		if len(message.Blocks) == 0 {
+			fmt.Fprintf(&b, "%s: %s\n", name, message.Content)
+			continue
+		}
+		for _, block := range message.Blocks {
+			switch block.Type {
+			case models.TextBlock:
+				fmt.Fprintf(&b, "%s: %s\n", name, block.Text)
+			case models.ToolUseBlock:
+				fmt.Fprintf(&b, "%s called tool %s with %s\n", name, block.Name, block.Input)
+			case models.ToolResultBlock:
+				fmt.Fprintf(&b, "Tool result: %s\n", block.Content)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,319 @@
+// pkg/cost/budget.go
+package cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/tokens"
+)
+
+// Budget periods.
+const (
+	Daily   = "daily"
+	Monthly = "monthly"
+)
+
+// Budget is a spending limit in US dollars. A zero limit means unlimited.
+type Budget struct {
+	Daily   float64
+	Monthly float64
+}
+
+// BudgetExceededError is returned when a tenant has spent its budget for a period.
+type BudgetExceededError struct {
+	Tenant string
+	Period string
+	Limit  float64
+	Spent  float64
+}
+
+func (e BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s budget of $%.2f exceeded for tenant %q: spent $%.2f", e.Period, e.Limit, e.Tenant, e.Spent)
+}
+
+// SpendStore records spend per tenant and period key. Implement it to share
+// budgets across processes.
+type SpendStore interface {
+	Add(tenant, period string, amount float64) error
+	Get(tenant, period string) (float64, error)
+}
+
+// MemoryStore is an in-process SpendStore.
+type MemoryStore struct {
+	mu    sync.Mutex
+	spend map[string]float64
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{spend: make(map[string]float64)}
+}
+
+// Add implements SpendStore.
+func (s *MemoryStore) Add(tenant, period string, amount float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spend[tenant+"/"+period] += amount
+	return nil
+}
+
+// Get implements SpendStore.
+func (s *MemoryStore) Get(tenant, period string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spend[tenant+"/"+period], nil
+}
+
+// Guard enforces per-tenant budgets on an api.Client. The tenant of a request
+// is taken from its metadata user_id unless Tenant is set.
+type Guard struct {
+	Calculator *Calculator
+	Store      SpendStore
+	// Budgets holds per-tenant limits. Tenants without an entry use Default.
+	Budgets map[string]Budget
+	Default Budget
+	// Downgrade maps a model to a cheaper one used instead of rejecting
+	// requests once the budget is exhausted.
+	Downgrade map[string]string
+	// Tenant extracts the tenant key of a request.
+	Tenant func(req *models.MessageRequest) string
+	// Now returns the current time and is used to pick the period.
+	Now func() time.Time
+
+	// mu makes checking and reserving a request's spend atomic.
+	mu sync.Mutex
+}
+
+// NewGuard creates a Guard with in-memory spend tracking and default prices.
+func NewGuard(defaultBudget Budget) *Guard {
+	return &Guard{
+		Calculator: NewCalculator(nil),
+		Store:      NewMemoryStore(),
+		Budgets:    make(map[string]Budget),
+		Default:    defaultBudget,
+		Downgrade:  make(map[string]string),
+		Tenant:     MetadataTenant,
+		Now:        time.Now,
+	}
+}
+
+// MetadataTenant returns the metadata user_id of req.
+func MetadataTenant(req *models.MessageRequest) string {
+	if req.Metadata == nil {
+		return ""
+	}
+	return req.Metadata.UserID
+}
+
+// WithBudgetGuard returns a ClientOption that enforces g on every message call.
+func WithBudgetGuard(g *Guard) api.ClientOption {
+	return api.WithMiddleware(g.Middleware())
+}
+
+// periodKeys returns the storage keys of the current day and month.
+func (g *Guard) periodKeys() map[string]string {
+	now := g.Now().UTC()
+	return map[string]string{
+		Daily:   Daily + ":" + now.Format("2006-01-02"),
+		Monthly: Monthly + ":" + now.Format("2006-01"),
+	}
+}
+
+// Check returns a BudgetExceededError if tenant has no budget left.
+func (g *Guard) Check(tenant string) error {
+	budget, ok := g.Budgets[tenant]
+	if !ok {
+		budget = g.Default
+	}
+	keys := g.periodKeys()
+	limits := []struct {
+		period string
+		limit  float64
+	}{{Daily, budget.Daily}, {Monthly, budget.Monthly}}
+
+	for _, l := range limits {
+		if l.limit <= 0 {
+			continue
+		}
+		spent, err := g.Store.Get(tenant, keys[l.period])
+		if err != nil {
+			return fmt.Errorf("failed to read spend: %w", err)
+		}
+		if spent >= l.limit {
+			return BudgetExceededError{Tenant: tenant, Period: l.period, Limit: l.limit, Spent: spent}
+		}
+	}
+	return nil
+}
+
+// Record adds the cost of usage to the tenant's spend.
+func (g *Guard) Record(tenant, model string, usage models.MessageUsage) (Cost, error) {
+	c, err := g.Calculator.Cost(model, usage, false)
+	if err != nil {
+		return Cost{}, err
+	}
+	for _, key := range g.periodKeys() {
+		if err := g.Store.Add(tenant, key, c.Total()); err != nil {
+			return c, fmt.Errorf("failed to record spend: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Spent returns the tenant's spend in the current period.
+func (g *Guard) Spent(tenant, period string) (float64, error) {
+	return g.Store.Get(tenant, g.periodKeys()[period])
+}
+
+// Middleware returns middleware that rejects or downgrades over-budget
+// requests and records the cost of every response. Requests for models
+// missing from the price table are rejected, since their spend cannot be tracked.
+//
+// Before a request is sent, its estimated cost (the estimated prompt tokens
+// plus MaxTokens of output) is reserved against the tenant's spend, so that
+// concurrent requests see each other and cannot all pass Check on the same
+// remaining budget. The reservation is replaced by the actual cost once the
+// response or stream completes, and released if the call fails. Checking and
+// reserving are atomic within one Guard only; Guards in several processes
+// sharing a SpendStore can still overshoot by the requests in flight. A
+// downgraded request is admitted even though the budget is exhausted, so it
+// may take the spend past the limit.
+func (g *Guard) Middleware() api.Middleware {
+	return func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+		req := call.MessageRequest()
+		if req == nil || call.Operation == "CountTokens" {
+			return next(ctx, call)
+		}
+		tenant := g.Tenant(req)
+
+		g.mu.Lock()
+		if err := g.Check(tenant); err != nil {
+			cheaper, ok := g.Downgrade[req.Model]
+			if _, exceeded := err.(BudgetExceededError); !exceeded || !ok {
+				g.mu.Unlock()
+				return nil, err
+			}
+			// Downgrade a copy so the caller's request keeps its model.
+			downgraded := *req
+			downgraded.Model = cheaper
+			req = &downgraded
+			call.Params = req
+		}
+		res, err := g.reserve(tenant, req)
+		g.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := next(ctx, call)
+		if err != nil {
+			if releaseErr := g.settle(res, req.Model, models.MessageUsage{}); releaseErr != nil {
+				err = errors.Join(err, releaseErr)
+			}
+			return result, err
+		}
+
+		if call.Stream && result.Stream != nil {
+			result.Stream, result.StreamErrors = g.recordStream(res, req.Model, result.Stream, result.StreamErrors)
+			return result, nil
+		}
+		model, usage := req.Model, models.MessageUsage{}
+		if resp := result.MessageResponse(); resp != nil {
+			if resp.Model != "" {
+				model = resp.Model
+			}
+			usage = resp.Usage
+		}
+		if err := g.settle(res, model, usage); err != nil {
+			return result, err
+		}
+		return result, nil
+	}
+}
+
+// reservation is the estimated cost held against a tenant's spend while a
+// request is in flight.
+type reservation struct {
+	tenant string
+	keys   map[string]string
+	amount float64
+}
+
+// reserve adds the estimated cost of req to the tenant's spend. It must be
+// called with g.mu held.
+func (g *Guard) reserve(tenant string, req *models.MessageRequest) (reservation, error) {
+	estimate, err := g.Calculator.Cost(req.Model, models.MessageUsage{
+		PromptTokens:     tokens.DefaultEstimator.Estimate(req),
+		CompletionTokens: req.MaxTokens,
+	}, false)
+	if err != nil {
+		return reservation{}, err
+	}
+	res := reservation{tenant: tenant, keys: g.periodKeys(), amount: estimate.Total()}
+	for _, key := range res.keys {
+		if err := g.Store.Add(tenant, key, res.amount); err != nil {
+			return reservation{}, fmt.Errorf("failed to reserve spend: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// settle replaces a reservation with the cost of usage, recorded in the
+// periods the reservation was made in. Zero usage releases the reservation.
+func (g *Guard) settle(res reservation, model string, usage models.MessageUsage) error {
+	c, err := g.Calculator.Cost(model, usage, false)
+	if err != nil {
+		return err
+	}
+	for _, key := range res.keys {
+		if err := g.Store.Add(res.tenant, key, c.Total()-res.amount); err != nil {
+			return fmt.Errorf("failed to record spend: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordStream forwards stream chunks and errors and settles the reservation
+// with the final usage once the stream is drained. A failure to record the
+// spend is sent on the returned error channel.
+func (g *Guard) recordStream(res reservation, model string, stream <-chan models.MessageResponse, errs <-chan error) (<-chan models.MessageResponse, <-chan error) {
+	output := make(chan models.MessageResponse)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+
+		var usage models.MessageUsage
+		for stream != nil || errs != nil {
+			select {
+			case chunk, ok := <-stream:
+				if !ok {
+					stream = nil
+					continue
+				}
+				if chunk.Usage != (models.MessageUsage{}) {
+					usage = chunk.Usage
+				}
+				output <- chunk
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				errCh <- err
+			}
+		}
+		if err := g.settle(res, model, usage); err != nil {
+			errCh <- err
+		}
+	}()
+
+	return output, errCh
+}
@@ -0,0 +1,122 @@
+// pkg/cost/cost.go
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+const tokensPerMillion = 1_000_000
+
+// Pricing is the price of a model in US dollars per million tokens.
+type Pricing struct {
+	Input      float64
+	Output     float64
+	CacheWrite float64
+	CacheRead  float64
+	// BatchDiscount is the fraction taken off every price for requests sent
+	// through the Message Batches API, e.g. 0.5 for half price.
+	BatchDiscount float64
+}
+
+// PriceTable maps model IDs or model ID prefixes to their pricing.
+type PriceTable map[string]Pricing
+
+// DefaultPrices lists public list prices. Keys are prefixes, so dated model
+// versions such as claude-3-5-sonnet-20241022 match claude-3-5-sonnet. Models
+// priced differently from an older model whose ID is a prefix of theirs, such
+// as claude-opus-4-5 and claude-opus-4, need their own entry.
+var DefaultPrices = PriceTable{
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50, BatchDiscount: 0.5},
+	"claude-opus-4-1":   {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50, BatchDiscount: 0.5},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50, BatchDiscount: 0.5},
+	"claude-sonnet-4-5": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30, BatchDiscount: 0.5},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30, BatchDiscount: 0.5},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10, BatchDiscount: 0.5},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30, BatchDiscount: 0.5},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30, BatchDiscount: 0.5},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08, BatchDiscount: 0.5},
+	"claude-3-opus":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50, BatchDiscount: 0.5},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03, BatchDiscount: 0.5},
+}
+
+// Lookup returns the pricing of model. An exact match wins, otherwise the
+// longest matching prefix is used.
+func (t PriceTable) Lookup(model string) (Pricing, bool) {
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+	prefixes := make([]string, 0, len(t))
+	for prefix := range t {
+		if strings.HasPrefix(model, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return Pricing{}, false
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return t[prefixes[0]], true
+}
+
+// Cost is the price of a single response in US dollars, broken down by token type.
+type Cost struct {
+	Input      float64
+	Output     float64
+	CacheWrite float64
+	CacheRead  float64
+}
+
+// Total returns the sum of all components.
+func (c Cost) Total() float64 {
+	return c.Input + c.Output + c.CacheWrite + c.CacheRead
+}
+
+// Add returns the component-wise sum of c and other.
+func (c Cost) Add(other Cost) Cost {
+	return Cost{
+		Input:      c.Input + other.Input,
+		Output:     c.Output + other.Output,
+		CacheWrite: c.CacheWrite + other.CacheWrite,
+		CacheRead:  c.CacheRead + other.CacheRead,
+	}
+}
+
+// Calculator prices token usage.
+type Calculator struct {
+	Prices PriceTable
+}
+
+// NewCalculator creates a Calculator using prices, or DefaultPrices when nil.
+func NewCalculator(prices PriceTable) *Calculator {
+	if prices == nil {
+		prices = DefaultPrices
+	}
+	return &Calculator{Prices: prices}
+}
+
+// Cost prices usage for model. batch applies the model's batch discount.
+func (c *Calculator) Cost(model string, usage models.MessageUsage, batch bool) (Cost, error) {
+	p, ok := c.Prices.Lookup(model)
+	if !ok {
+		return Cost{}, fmt.Errorf("no pricing for model %q", model)
+	}
+	factor := 1.0 / tokensPerMillion
+	if batch {
+		factor *= 1 - p.BatchDiscount
+	}
+	return Cost{
+		Input:      float64(usage.PromptTokens) * p.Input * factor,
+		Output:     float64(usage.CompletionTokens) * p.Output * factor,
+		CacheWrite: float64(usage.CacheCreationInputTokens) * p.CacheWrite * factor,
+		CacheRead:  float64(usage.CacheReadInputTokens) * p.CacheRead * factor,
+	}, nil
+}
+
+// ResponseCost prices a message response using the model it reports.
+func (c *Calculator) ResponseCost(resp *models.MessageResponse) (Cost, error) {
+	return c.Cost(resp.Model, resp.Usage, false)
+}
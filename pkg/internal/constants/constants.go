@@ -9,4 +9,10 @@ const (
     MaxRetries     = 3
     MinRetryDelay  = 1 * time.Second
     MaxRetryDelay  = 30 * time.Second
+
+    StreamConnectTimeout    = 10 * time.Second
+    StreamFirstEventTimeout = 60 * time.Second
+    StreamIdleTimeout       = 60 * time.Second
+    TimeoutPerOutputToken   = 30 * time.Millisecond
+    MaxScaledTimeout        = 10 * time.Minute
 )
@@ -0,0 +1,125 @@
+// pkg/internal/logging/level.go
+package logging
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// LevelLogger drops messages below a minimum level before passing the rest
+// to the wrapped Logger. Fatal messages are never dropped.
+type LevelLogger struct {
+	logger Logger
+	min    Level
+}
+
+// NewLevelLogger wraps logger so that only messages at or above min are logged.
+func NewLevelLogger(logger Logger, min Level) *LevelLogger {
+	return &LevelLogger{logger: logger, min: min}
+}
+
+func (l *LevelLogger) Debugf(format string, args ...interface{}) {
+	if l.min <= LevelDebug {
+		l.logger.Debugf(format, args...)
+	}
+}
+
+func (l *LevelLogger) Infof(format string, args ...interface{}) {
+	if l.min <= LevelInfo {
+		l.logger.Infof(format, args...)
+	}
+}
+
+func (l *LevelLogger) Warnf(format string, args ...interface{}) {
+	if l.min <= LevelWarn {
+		l.logger.Warnf(format, args...)
+	}
+}
+
+func (l *LevelLogger) Errorf(format string, args ...interface{}) {
+	if l.min <= LevelError {
+		l.logger.Errorf(format, args...)
+	}
+}
+
+func (l *LevelLogger) Fatalf(format string, args ...interface{}) {
+	l.logger.Fatalf(format, args...)
+}
+
+// With implements FieldLogger.
+func (l *LevelLogger) With(args ...interface{}) Logger {
+	return &LevelLogger{logger: With(l.logger, args...), min: l.min}
+}
+
+// FieldLogger is implemented by loggers that can attach structured key-value
+// fields to every message they log.
+type FieldLogger interface {
+	Logger
+	With(args ...interface{}) Logger
+}
+
+// With returns a Logger that attaches the alternating key-value pairs in args
+// to every message. Loggers that do not implement FieldLogger get the fields
+// appended to the formatted message as key=value pairs.
+func With(logger Logger, args ...interface{}) Logger {
+	if len(args) == 0 {
+		return logger
+	}
+	if fl, ok := logger.(FieldLogger); ok {
+		return fl.With(args...)
+	}
+	return &fieldLogger{logger: logger, suffix: formatFields(args)}
+}
+
+// fieldLogger appends formatted fields to messages of a printf-only Logger.
+type fieldLogger struct {
+	logger Logger
+	suffix string
+}
+
+func (l *fieldLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debugf(format+"%s", append(args, l.suffix)...)
+}
+
+func (l *fieldLogger) Infof(format string, args ...interface{}) {
+	l.logger.Infof(format+"%s", append(args, l.suffix)...)
+}
+
+func (l *fieldLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Warnf(format+"%s", append(args, l.suffix)...)
+}
+
+func (l *fieldLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Errorf(format+"%s", append(args, l.suffix)...)
+}
+
+func (l *fieldLogger) Fatalf(format string, args ...interface{}) {
+	l.logger.Fatalf(format+"%s", append(args, l.suffix)...)
+}
+
+func (l *fieldLogger) With(args ...interface{}) Logger {
+	return &fieldLogger{logger: l.logger, suffix: l.suffix + formatFields(args)}
+}
+
+func formatFields(args []interface{}) string {
+	var b strings.Builder
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+	return b.String()
+}
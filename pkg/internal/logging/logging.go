@@ -58,5 +58,5 @@ func (l *StdLogger) Errorf(format string, args ...interface{}) {
 }
 
 func (l *StdLogger) Fatalf(format string, args ...interface{}) {
-    l.fatal.Printf(format, args...)
+    l.fatal.Fatalf(format, args...)
 }
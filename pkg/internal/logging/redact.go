@@ -0,0 +1,109 @@
+// pkg/internal/logging/redact.go
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var apiKeyPattern = regexp.MustCompile(`sk-ant-[A-Za-z0-9_\-]+`)
+
+// contentKeys are the JSON fields that carry user or model text.
+var contentKeys = map[string]bool{
+	"content":      true,
+	"text":         true,
+	"prompt":       true,
+	"system":       true,
+	"completion":   true,
+	"thinking":     true,
+	"partial_json": true,
+}
+
+// secretKeys are the JSON fields that carry credentials.
+var secretKeys = map[string]bool{
+	"api_key":       true,
+	"x-api-key":     true,
+	"authorization": true,
+}
+
+// RedactionPolicy controls what is masked before request and response data is logged.
+type RedactionPolicy struct {
+	// APIKeys masks anything that looks like an Anthropic API key.
+	APIKeys bool
+	// Media replaces base64 image and document data with its size.
+	Media bool
+	// Content replaces message text with its length.
+	Content bool
+}
+
+// DefaultRedactionPolicy masks API keys, media and message content.
+var DefaultRedactionPolicy = RedactionPolicy{APIKeys: true, Media: true, Content: true}
+
+// RedactString masks API keys in s.
+func (p RedactionPolicy) RedactString(s string) string {
+	if !p.APIKeys {
+		return s
+	}
+	return apiKeyPattern.ReplaceAllString(s, "sk-ant-***")
+}
+
+// RedactJSON masks the fields of a JSON document selected by the policy. A
+// document that cannot be parsed is returned with only API keys masked.
+func (p RedactionPolicy) RedactJSON(data []byte) []byte {
+	if !p.Media && !p.Content && !p.APIKeys {
+		return data
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []byte(p.RedactString(string(data)))
+	}
+	redacted, err := json.Marshal(p.redactValue("", v))
+	if err != nil {
+		return []byte(p.RedactString(string(data)))
+	}
+	return redacted
+}
+
+// Redact marshals v to JSON and masks it. It is meant for logging request and
+// response structs.
+func (p RedactionPolicy) Redact(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<unloggable %T>", v)
+	}
+	return string(p.RedactJSON(data))
+}
+
+func (p RedactionPolicy) redactValue(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		isBase64 := value["type"] == "base64"
+		for k, field := range value {
+			if p.Media && isBase64 && k == "data" {
+				if s, ok := field.(string); ok {
+					value[k] = fmt.Sprintf("[base64 %d bytes]", len(s))
+					continue
+				}
+			}
+			value[k] = p.redactValue(k, field)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = p.redactValue(key, item)
+		}
+		return value
+	case string:
+		lower := strings.ToLower(key)
+		if p.APIKeys && secretKeys[lower] {
+			return "***"
+		}
+		if p.Content && contentKeys[lower] {
+			return fmt.Sprintf("[redacted %d chars]", len(value))
+		}
+		return p.RedactString(value)
+	}
+	return v
+}
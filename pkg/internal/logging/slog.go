@@ -0,0 +1,64 @@
+// pkg/internal/logging/slog.go
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// SlogLogger adapts a *slog.Logger to the Logger interface. Level filtering is
+// left to the slog handler. Messages and string field values pass through the
+// redaction policy before they are logged.
+type SlogLogger struct {
+	logger *slog.Logger
+	policy RedactionPolicy
+}
+
+// NewSlogLogger creates a Logger backed by logger that redacts with policy.
+func NewSlogLogger(logger *slog.Logger, policy RedactionPolicy) *SlogLogger {
+	return &SlogLogger{logger: logger, policy: policy}
+}
+
+func (l *SlogLogger) log(level slog.Level, format string, args []interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	l.logger.Log(ctx, level, l.policy.RedactString(fmt.Sprintf(format, args...)))
+}
+
+func (l *SlogLogger) Debugf(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, format, args)
+}
+
+func (l *SlogLogger) Infof(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, format, args)
+}
+
+func (l *SlogLogger) Warnf(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, format, args)
+}
+
+func (l *SlogLogger) Errorf(format string, args ...interface{}) {
+	l.log(slog.LevelError, format, args)
+}
+
+// Fatalf logs at error level and exits the process.
+func (l *SlogLogger) Fatalf(format string, args ...interface{}) {
+	l.log(slog.LevelError, format, args)
+	os.Exit(1)
+}
+
+// With implements FieldLogger. String values are redacted.
+func (l *SlogLogger) With(args ...interface{}) Logger {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok && i%2 == 1 {
+			arg = l.policy.RedactString(s)
+		}
+		redacted[i] = arg
+	}
+	return &SlogLogger{logger: l.logger.With(redacted...), policy: l.policy}
+}
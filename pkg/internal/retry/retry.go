@@ -49,7 +49,7 @@ func (r *ExponentialBackoffRetrier) Do(fn func() (*http.Response, error)) (*http
             break
         }
 
-        delay := r.MinRetryDelay * time.Duration(rand.Float64()*(1<<float64(i))) 
+        delay := r.MinRetryDelay * time.Duration(rand.Float64()*float64(int(1)<<uint(i))) 
         if delay > r.MaxRetryDelay {
             delay = r.MaxRetryDelay
         }
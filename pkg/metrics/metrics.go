@@ -0,0 +1,173 @@
+// pkg/metrics/metrics.go
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// Metric names reported by the Collector.
+const (
+	RequestsTotal          = "anthropic_requests_total"
+	RequestDuration        = "anthropic_request_duration_seconds"
+	TimeToFirstToken       = "anthropic_time_to_first_token_seconds"
+	OutputTokensPerSecond  = "anthropic_output_tokens_per_second"
+	TokensTotal            = "anthropic_tokens_total"
+	RetriesTotal           = "anthropic_retries_total"
+	RateLimitMetricPrefix  = "anthropic_ratelimit_"
+	rateLimitHeaderPrefix  = "anthropic-ratelimit-"
+	statusError            = "error"
+	tokenTypeInput         = "input"
+	tokenTypeOutput        = "output"
+	tokenTypeCacheCreation = "cache_creation"
+	tokenTypeCacheRead     = "cache_read"
+)
+
+// Labels are the dimensions of a single measurement.
+type Labels map[string]string
+
+// Backend stores measurements. Implement it to send metrics somewhere other
+// than the bundled Prometheus exporter.
+type Backend interface {
+	AddCounter(name string, labels Labels, value float64)
+	ObserveHistogram(name string, labels Labels, value float64)
+	SetGauge(name string, labels Labels, value float64)
+}
+
+// Collector measures the traffic of an api.Client and reports it to a Backend.
+type Collector struct {
+	backend Backend
+	now     func() time.Time
+}
+
+// NewCollector creates a Collector that reports to backend.
+func NewCollector(backend Backend) *Collector {
+	return &Collector{backend: backend, now: time.Now}
+}
+
+// WithMetrics returns a ClientOption that instruments the client with c.
+func WithMetrics(c *Collector) api.ClientOption {
+	return func(client *api.Client) {
+		client.Middleware = append(client.Middleware, c.Middleware())
+	}
+}
+
+// Middleware returns middleware that records request, latency, token, retry
+// and rate-limit metrics for every call. Retries are taken from the attempt
+// count of the call, so they are counted whichever retrier made them.
+func (c *Collector) Middleware() api.Middleware {
+	return func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+		start := c.now()
+		labels := Labels{"endpoint": endpoint(call.Path), "model": ""}
+		if req := call.MessageRequest(); req != nil {
+			labels["model"] = req.Model
+		}
+
+		result, err := next(ctx, call)
+
+		status := statusError
+		if result != nil && result.HTTPResponse != nil {
+			status = strconv.Itoa(result.HTTPResponse.StatusCode)
+			c.observeRateLimits(result.HTTPResponse.Header)
+		}
+		requestLabels := Labels{"endpoint": labels["endpoint"], "model": labels["model"], "status": status}
+		c.backend.AddCounter(RequestsTotal, requestLabels, 1)
+		if result != nil && result.Metadata != nil && result.Metadata.Attempts > 1 {
+			c.backend.AddCounter(RetriesTotal, requestLabels, float64(result.Metadata.Attempts-1))
+		}
+
+		if err != nil {
+			c.backend.ObserveHistogram(RequestDuration, requestLabels, c.now().Sub(start).Seconds())
+			return result, err
+		}
+		if call.Stream && result.Stream != nil {
+			result.Stream = c.observeStream(start, requestLabels, result.Stream)
+			return result, nil
+		}
+
+		c.backend.ObserveHistogram(RequestDuration, requestLabels, c.now().Sub(start).Seconds())
+		if resp := result.MessageResponse(); resp != nil {
+			c.observeUsage(labels["model"], resp.Usage)
+		}
+		return result, nil
+	}
+}
+
+// observeStream forwards stream chunks while timing the first token and the
+// output rate. The request duration covers the whole stream.
+func (c *Collector) observeStream(start time.Time, labels Labels, input <-chan models.MessageResponse) <-chan models.MessageResponse {
+	output := make(chan models.MessageResponse)
+	model := Labels{"model": labels["model"]}
+
+	go func() {
+		defer close(output)
+
+		var first time.Time
+		var usage models.MessageUsage
+		for chunk := range input {
+			if first.IsZero() {
+				first = c.now()
+				c.backend.ObserveHistogram(TimeToFirstToken, model, first.Sub(start).Seconds())
+			}
+			if chunk.Usage != (models.MessageUsage{}) {
+				usage = chunk.Usage
+			}
+			output <- chunk
+		}
+
+		end := c.now()
+		c.backend.ObserveHistogram(RequestDuration, labels, end.Sub(start).Seconds())
+		c.observeUsage(labels["model"], usage)
+		if elapsed := end.Sub(first).Seconds(); !first.IsZero() && elapsed > 0 && usage.CompletionTokens > 0 {
+			c.backend.ObserveHistogram(OutputTokensPerSecond, model, float64(usage.CompletionTokens)/elapsed)
+		}
+	}()
+
+	return output
+}
+
+func (c *Collector) observeUsage(model string, usage models.MessageUsage) {
+	counts := map[string]int{
+		tokenTypeInput:         usage.PromptTokens,
+		tokenTypeOutput:        usage.CompletionTokens,
+		tokenTypeCacheCreation: usage.CacheCreationInputTokens,
+		tokenTypeCacheRead:     usage.CacheReadInputTokens,
+	}
+	for tokenType, n := range counts {
+		if n > 0 {
+			c.backend.AddCounter(TokensTotal, Labels{"model": model, "type": tokenType}, float64(n))
+		}
+	}
+}
+
+// observeRateLimits records every numeric anthropic-ratelimit-* header as a
+// gauge, e.g. anthropic-ratelimit-tokens-remaining as
+// anthropic_ratelimit_tokens_remaining.
+func (c *Collector) observeRateLimits(header http.Header) {
+	for key, values := range header {
+		key = strings.ToLower(key)
+		if !strings.HasPrefix(key, rateLimitHeaderPrefix) || len(values) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			continue
+		}
+		name := RateLimitMetricPrefix + strings.ReplaceAll(strings.TrimPrefix(key, rateLimitHeaderPrefix), "-", "_")
+		c.backend.SetGauge(name, Labels{}, value)
+	}
+}
+
+// endpoint strips the query string from a request path.
+func endpoint(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
@@ -0,0 +1,175 @@
+// pkg/metrics/prometheus.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram bucket upper bounds used by the Prometheus
+// exporter when none are given. They suit latencies in seconds.
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+type series struct {
+	labels string
+	value  float64
+	// Histogram state.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type family struct {
+	kind   string
+	series map[string]*series
+}
+
+// PrometheusExporter is a Backend that serves the collected metrics in the
+// Prometheus text exposition format.
+type PrometheusExporter struct {
+	mu       sync.Mutex
+	buckets  []float64
+	families map[string]*family
+}
+
+// NewPrometheusExporter creates an exporter. Histograms use buckets, or
+// DefaultBuckets when buckets is empty.
+func NewPrometheusExporter(buckets ...float64) *PrometheusExporter {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &PrometheusExporter{
+		buckets:  sorted,
+		families: make(map[string]*family),
+	}
+}
+
+// AddCounter implements Backend.
+func (e *PrometheusExporter) AddCounter(name string, labels Labels, value float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.series("counter", name, labels).value += value
+}
+
+// ObserveHistogram implements Backend.
+func (e *PrometheusExporter) ObserveHistogram(name string, labels Labels, value float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := e.series("histogram", name, labels)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(e.buckets))
+	}
+	for i, bound := range e.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// SetGauge implements Backend.
+func (e *PrometheusExporter) SetGauge(name string, labels Labels, value float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.series("gauge", name, labels).value = value
+}
+
+func (e *PrometheusExporter) series(kind, name string, labels Labels) *series {
+	f, ok := e.families[name]
+	if !ok {
+		f = &family{kind: kind, series: make(map[string]*series)}
+		e.families[name] = f
+	}
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: key}
+		f.series[key] = s
+	}
+	return s
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (e *PrometheusExporter) WriteTo(w io.Writer) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var b strings.Builder
+	names := make([]string, 0, len(e.families))
+	for name := range e.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := e.families[name]
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.kind)
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != "histogram" {
+				fmt.Fprintf(&b, "%s%s %s\n", name, braces(s.labels), formatFloat(s.value))
+				continue
+			}
+			for i, bound := range e.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(joinLabels(s.labels, `le="`+formatFloat(bound)+`"`)), s.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(joinLabels(s.labels, `le="+Inf"`)), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, braces(s.labels), formatFloat(s.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, braces(s.labels), s.count)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics, so the exporter can be mounted at /metrics.
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = e.WriteTo(w)
+}
+
+// formatLabels renders labels sorted by name, without braces.
+func formatLabels(labels Labels) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + strconv.Quote(labels[name])
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
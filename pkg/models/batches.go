@@ -0,0 +1,64 @@
+// pkg/models/batches.go
+package models
+
+// CountTokensResponse is the result of counting the tokens of a MessageRequest.
+type CountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+// Batch processing statuses.
+const (
+	BatchInProgress = "in_progress"
+	BatchCanceling  = "canceling"
+	BatchEnded      = "ended"
+)
+
+// BatchRequest is a single message request within a batch.
+type BatchRequest struct {
+	CustomID string         `json:"custom_id"`
+	Params   MessageRequest `json:"params"`
+}
+
+// CreateBatchRequest creates a message batch.
+type CreateBatchRequest struct {
+	Requests []BatchRequest `json:"requests"`
+}
+
+// BatchRequestCounts tallies the requests of a batch by outcome.
+type BatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// Batch is a message batch.
+type Batch struct {
+	ID               string             `json:"id"`
+	Type             string             `json:"type"`
+	ProcessingStatus string             `json:"processing_status"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	CreatedAt        string             `json:"created_at"`
+	EndedAt          string             `json:"ended_at,omitempty"`
+	ResultsURL       string             `json:"results_url,omitempty"`
+}
+
+// BatchError describes why a batched request failed.
+type BatchError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// BatchResultOutcome is the outcome of a single batched request.
+type BatchResultOutcome struct {
+	Type    string           `json:"type"`
+	Message *MessageResponse `json:"message,omitempty"`
+	Error   *BatchError      `json:"error,omitempty"`
+}
+
+// BatchResult is one line of a batch results file.
+type BatchResult struct {
+	CustomID string             `json:"custom_id"`
+	Result   BatchResultOutcome `json:"result"`
+}
@@ -0,0 +1,232 @@
+// pkg/models/builder.go
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// RequestBuilder builds a MessageRequest with chained calls:
+//
+//	req, err := models.NewRequest("claude-sonnet-4-20250514", 1024).
+//		System("You are a careful reviewer.").
+//		User("What is in this picture?").
+//		Image("image/png", png).
+//		Build()
+//
+// Content methods such as Text and Image add a block to the current turn, or
+// start a user turn if there is none. Mistakes are collected and reported
+// together by Build.
+type RequestBuilder struct {
+	req  MessageRequest
+	errs []error
+	// cache points at the cache control of the block or tool added last.
+	// Every append that may move it resets it.
+	cache **CacheControl
+}
+
+// NewRequest starts a request for model with the given max_tokens.
+func NewRequest(model string, maxTokens int) *RequestBuilder {
+	b := &RequestBuilder{}
+	b.req.Model = model
+	b.req.MaxTokens = maxTokens
+	return b
+}
+
+// System sets the system prompt.
+func (b *RequestBuilder) System(text string) *RequestBuilder {
+	b.req.System = text
+	return b
+}
+
+// User starts a user turn. A non-empty text becomes its first block.
+func (b *RequestBuilder) User(text string) *RequestBuilder {
+	return b.turn(UserRole, text)
+}
+
+// Assistant starts an assistant turn. As the last turn it is a prefill that
+// the model continues.
+func (b *RequestBuilder) Assistant(text string) *RequestBuilder {
+	return b.turn(AssistantRole, text)
+}
+
+func (b *RequestBuilder) turn(role MessageRoleType, text string) *RequestBuilder {
+	b.req.Messages = append(b.req.Messages, Message{Role: role})
+	b.cache = nil
+	if text != "" {
+		b.Text(text)
+	}
+	return b
+}
+
+// Text adds a text block to the current turn.
+func (b *RequestBuilder) Text(text string) *RequestBuilder {
+	return b.block(UserRole, ContentBlock{Type: TextBlock, Text: text})
+}
+
+// Image adds an image with the given media type, e.g. "image/png".
+func (b *RequestBuilder) Image(mediaType string, data []byte) *RequestBuilder {
+	return b.block(UserRole, ContentBlock{Type: ImageBlock, Source: &Source{
+		Type:      Base64Source,
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}})
+}
+
+// ImageURL adds an image referenced by URL.
+func (b *RequestBuilder) ImageURL(url string) *RequestBuilder {
+	return b.block(UserRole, ContentBlock{Type: ImageBlock, Source: &Source{Type: URLSource, URL: url}})
+}
+
+// Document adds a document with the given media type, e.g. "application/pdf".
+func (b *RequestBuilder) Document(mediaType string, data []byte) *RequestBuilder {
+	return b.block(UserRole, ContentBlock{Type: DocumentBlock, Source: &Source{
+		Type:      Base64Source,
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}})
+}
+
+// DocumentURL adds a document referenced by URL.
+func (b *RequestBuilder) DocumentURL(url string) *RequestBuilder {
+	return b.block(UserRole, ContentBlock{Type: DocumentBlock, Source: &Source{Type: URLSource, URL: url}})
+}
+
+// DocumentText adds a plain text document.
+func (b *RequestBuilder) DocumentText(text string) *RequestBuilder {
+	return b.block(UserRole, ContentBlock{Type: DocumentBlock, Source: &Source{Type: TextSource, MediaType: "text/plain", Data: text}})
+}
+
+// ToolUse adds a tool call to the current assistant turn, starting one if
+// needed. input is encoded as JSON.
+func (b *RequestBuilder) ToolUse(id, name string, input interface{}) *RequestBuilder {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("tool_use %s: failed to encode input: %w", id, err))
+		return b
+	}
+	if n := len(b.req.Messages); n == 0 || b.req.Messages[n-1].Role != AssistantRole {
+		b.turn(AssistantRole, "")
+	}
+	return b.block(AssistantRole, ContentBlock{Type: ToolUseBlock, ID: id, Name: name, Input: raw})
+}
+
+// ToolResult answers the tool call id in the current user turn, starting one
+// if needed.
+func (b *RequestBuilder) ToolResult(id, content string, isError bool) *RequestBuilder {
+	if n := len(b.req.Messages); n == 0 || b.req.Messages[n-1].Role != UserRole {
+		b.turn(UserRole, "")
+	}
+	return b.block(UserRole, ContentBlock{Type: ToolResultBlock, ToolUseID: id, Content: content, IsError: isError})
+}
+
+// block appends block to the current turn, starting a turn with role if
+// there is none.
+func (b *RequestBuilder) block(role MessageRoleType, block ContentBlock) *RequestBuilder {
+	if len(b.req.Messages) == 0 {
+		b.turn(role, "")
+	}
+	message := &b.req.Messages[len(b.req.Messages)-1]
+	message.Blocks = append(message.Blocks, block)
+	if block.Type == TextBlock {
+		message.Content += block.Text
+	}
+	b.cache = &message.Blocks[len(message.Blocks)-1].CacheControl
+	return b
+}
+
+// Tool makes a tool available. schema is the JSON Schema of its input, given
+// as json.RawMessage, []byte or any value encoded as JSON.
+func (b *RequestBuilder) Tool(name, description string, schema interface{}) *RequestBuilder {
+	var raw json.RawMessage
+	switch s := schema.(type) {
+	case json.RawMessage:
+		raw = s
+	case []byte:
+		raw = s
+	default:
+		var err error
+		if raw, err = json.Marshal(schema); err != nil {
+			b.errs = append(b.errs, fmt.Errorf("tool %s: failed to encode schema: %w", name, err))
+			return b
+		}
+	}
+	b.req.Tools = append(b.req.Tools, Tool{Name: name, Description: description, InputSchema: raw})
+	b.cache = &b.req.Tools[len(b.req.Tools)-1].CacheControl
+	return b
+}
+
+// ToolChoice sets how the model may use tools.
+func (b *RequestBuilder) ToolChoice(choice ToolChoice) *RequestBuilder {
+	b.req.ToolChoice = &choice
+	return b
+}
+
+// UseTool requires the model to call the tool name.
+func (b *RequestBuilder) UseTool(name string) *RequestBuilder {
+	return b.ToolChoice(ToolChoice{Type: ToolChoiceTool, Name: name})
+}
+
+// Thinking enables extended thinking with budgetTokens.
+func (b *RequestBuilder) Thinking(budgetTokens int) *RequestBuilder {
+	b.req.Thinking = &Thinking{Type: "enabled", BudgetTokens: budgetTokens}
+	return b
+}
+
+// Cache marks the block or tool added last as the end of a cached prompt
+// prefix.
+func (b *RequestBuilder) Cache() *RequestBuilder {
+	if b.cache == nil {
+		b.errs = append(b.errs, errors.New("Cache called before any block or tool was added"))
+		return b
+	}
+	*b.cache = &CacheControl{Type: "ephemeral"}
+	return b
+}
+
+// Temperature sets the sampling temperature.
+func (b *RequestBuilder) Temperature(temperature float32) *RequestBuilder {
+	b.req.Temperature = temperature
+	return b
+}
+
+// TopP sets nucleus sampling.
+func (b *RequestBuilder) TopP(topP float32) *RequestBuilder {
+	b.req.TopP = topP
+	return b
+}
+
+// TopK samples only from the k most likely tokens.
+func (b *RequestBuilder) TopK(k int) *RequestBuilder {
+	b.req.TopK = k
+	return b
+}
+
+// StopSequences sets custom sequences that stop generation.
+func (b *RequestBuilder) StopSequences(stop ...string) *RequestBuilder {
+	b.req.Stop = stop
+	return b
+}
+
+// UserID sets the end-user ID in the request metadata.
+func (b *RequestBuilder) UserID(id string) *RequestBuilder {
+	b.req.Metadata = &Metadata{UserID: id}
+	return b
+}
+
+// Build returns the request, or the builder mistakes and validation problems
+// joined in one error. Validation problems can be read with errors.As into
+// ValidationErrors.
+func (b *RequestBuilder) Build() (*MessageRequest, error) {
+	errs := append([]error(nil), b.errs...)
+	if err := b.req.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	req := b.req
+	return &req, nil
+}
@@ -0,0 +1,141 @@
+// pkg/models/content.go
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Content block types.
+const (
+	TextBlock       = "text"
+	ToolUseBlock    = "tool_use"
+	ToolResultBlock = "tool_result"
+	ImageBlock      = "image"
+	DocumentBlock   = "document"
+	// ThinkingBlock and RedactedThinkingBlock hold extended thinking.
+	ThinkingBlock         = "thinking"
+	RedactedThinkingBlock = "redacted_thinking"
+)
+
+// Source types of image and document blocks.
+const (
+	Base64Source = "base64"
+	URLSource    = "url"
+	TextSource   = "text"
+)
+
+// CacheControl marks the end of a prompt prefix to cache. Type is
+// "ephemeral"; TTL optionally extends the lifetime, e.g. "1h".
+type CacheControl struct {
+	Type string `json:"type"`
+	TTL  string `json:"ttl,omitempty"`
+}
+
+// Source is the data of an image or document block: inline as base64 Data,
+// referenced by URL, or plain text Data for a text document.
+type Source struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// ContentBlock is a single block of message content.
+type ContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// ToolUseID, Content and IsError describe a tool_result block.
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+	// Source holds the data of an image or document block.
+	Source       *Source       `json:"source,omitempty"`
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+	// Citations back the text of a text block with sources.
+	Citations []Citation `json:"citations,omitempty"`
+	// Thinking and Signature describe a thinking block; Data holds the
+	// encrypted content of a redacted_thinking block.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// Citation points from generated text to the part of a document or web page
+// it is based on. Which location fields are set depends on Type, e.g.
+// char_location, page_location or web_search_result_location.
+type Citation struct {
+	Type            string `json:"type"`
+	CitedText       string `json:"cited_text,omitempty"`
+	DocumentIndex   int    `json:"document_index,omitempty"`
+	DocumentTitle   string `json:"document_title,omitempty"`
+	StartCharIndex  int    `json:"start_char_index,omitempty"`
+	EndCharIndex    int    `json:"end_char_index,omitempty"`
+	StartPageNumber int    `json:"start_page_number,omitempty"`
+	EndPageNumber   int    `json:"end_page_number,omitempty"`
+	URL             string `json:"url,omitempty"`
+	Title           string `json:"title,omitempty"`
+}
+
+// Tool describes a tool the model may call. InputSchema is a JSON Schema
+// object describing the tool input.
+type Tool struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"input_schema"`
+	CacheControl *CacheControl   `json:"cache_control,omitempty"`
+}
+
+// messageJSON is the wire form of a Message whose content is a list of blocks.
+type messageJSON struct {
+	Role    MessageRoleType `json:"role"`
+	Content json.RawMessage `json:"content"`
+	Name    string          `json:"name,omitempty"`
+}
+
+// MarshalJSON encodes Content as a plain string unless the message has Blocks.
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m.Blocks) == 0 {
+		type plain Message
+		return json.Marshal(plain(m))
+	}
+	content, err := json.Marshal(m.Blocks)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(messageJSON{Role: m.Role, Content: content, Name: m.Name})
+}
+
+// UnmarshalJSON accepts content as a string or a list of blocks. For block
+// content, Content is set to the concatenated text of the text blocks.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw messageJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message{Role: raw.Role, Name: raw.Name}
+
+	content := strings.TrimSpace(string(raw.Content))
+	switch {
+	case content == "" || content == "null":
+	case content[0] == '[':
+		if err := json.Unmarshal(raw.Content, &m.Blocks); err != nil {
+			return err
+		}
+		var text strings.Builder
+		for _, block := range m.Blocks {
+			if block.Type == TextBlock {
+				text.WriteString(block.Text)
+			}
+		}
+		m.Content = text.String()
+	default:
+		if err := json.Unmarshal(raw.Content, &m.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// pkg/models/events.go
+package models
+
+// Stream event types sent by the Messages API.
+const (
+	MessageStartEvent      = "message_start"
+	MessageDeltaEvent      = "message_delta"
+	MessageStopEvent       = "message_stop"
+	ContentBlockStartEvent = "content_block_start"
+	ContentBlockDeltaEvent = "content_block_delta"
+	ContentBlockStopEvent  = "content_block_stop"
+	PingEvent              = "ping"
+	ErrorEvent             = "error"
+)
+
+// Delta types carried by content_block_delta events.
+const (
+	TextDelta      = "text_delta"
+	InputJSONDelta = "input_json_delta"
+)
+
+// StreamDelta is the incremental payload of a delta event.
+type StreamDelta struct {
+	Type        string `json:"type,omitempty"`
+	Text        string `json:"text,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"`
+}
+
+// StreamEvent is the decoded data of a single server-sent event.
+type StreamEvent struct {
+	Type         string           `json:"type"`
+	Index        int              `json:"index"`
+	Message      *MessageResponse `json:"message,omitempty"`
+	ContentBlock *ContentBlock    `json:"content_block,omitempty"`
+	Delta        *StreamDelta     `json:"delta,omitempty"`
+	Usage        *MessageUsage    `json:"usage,omitempty"`
+}
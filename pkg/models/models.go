@@ -2,6 +2,7 @@
 package models
 
 type CompletionRequest struct {
+    Model        string   `json:"model"`
     Prompt       string   `json:"prompt"`
     MaxTokens    int      `json:"max_tokens"`
     Temperature  float32  `json:"temperature"`
@@ -46,9 +47,38 @@ type Message struct {
     Role    MessageRoleType `json:"role"`
     Content string          `json:"content"`
     Name    string          `json:"name,omitempty"`
+    Blocks  []ContentBlock  `json:"-"`
+}
+
+type Metadata struct {
+    UserID string `json:"user_id,omitempty"`
+}
+
+// Thinking enables extended thinking with a budget of BudgetTokens, which
+// counts towards MaxTokens.
+type Thinking struct {
+    Type         string `json:"type"`
+    BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+// Tool choice types.
+const (
+    ToolChoiceAuto = "auto"
+    ToolChoiceAny  = "any"
+    ToolChoiceTool = "tool"
+    ToolChoiceNone = "none"
+)
+
+// ToolChoice controls whether and which tools the model must use. Name is
+// set for ToolChoiceTool.
+type ToolChoice struct {
+    Type                   string `json:"type"`
+    Name                   string `json:"name,omitempty"`
+    DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
 }
 
 type MessageRequest struct {
+    Model        string        `json:"model"`
     Messages     []Message     `json:"messages"`
     MaxTokens    int           `json:"max_tokens"`  
     N            int           `json:"n"`
@@ -58,6 +88,13 @@ type MessageRequest struct {
     Stream       bool          `json:"stream"`  
     FrequencyPenalty float32   `json:"frequency_penalty"`
     PresencePenalty float32    `json:"presence_penalty"`
+    Metadata     *Metadata     `json:"metadata,omitempty"`
+    System       string        `json:"system,omitempty"`
+    Tools        []Tool        `json:"tools,omitempty"`
+    Thinking     *Thinking     `json:"thinking,omitempty"`
+    ToolChoice   *ToolChoice   `json:"tool_choice,omitempty"`
+    TopK         int           `json:"top_k,omitempty"`
+}
 
 type MessageResponse struct {
     ID      string         `json:"id"`
@@ -78,6 +115,8 @@ type MessageUsage struct {
     PromptTokens     int `json:"prompt_tokens"`  
     CompletionTokens int `json:"completion_tokens"`
     TotalTokens      int `json:"total_tokens"`
+    CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+    CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 type Model struct {
@@ -0,0 +1,149 @@
+// pkg/models/response.go
+package models
+
+import "encoding/json"
+
+// StopReason is why the model stopped generating.
+type StopReason string
+
+// Stop reasons reported in MessageChoice.FinishReason.
+const (
+	StopEndTurn   StopReason = "end_turn"
+	StopMaxTokens StopReason = "max_tokens"
+	StopSequence  StopReason = "stop_sequence"
+	StopToolUse   StopReason = "tool_use"
+	StopPauseTurn StopReason = "pause_turn"
+	StopRefusal   StopReason = "refusal"
+	// StopUnknown is returned for a response without choices.
+	StopUnknown StopReason = ""
+)
+
+// Unfinished reports whether the turn was cut short and can be continued by
+// sending the output back as an assistant prefill.
+func (s StopReason) Unfinished() bool {
+	return s == StopMaxTokens || s == StopPauseTurn
+}
+
+// message returns the message of the first choice, or nil.
+func (r *MessageResponse) message() *Message {
+	if r == nil || len(r.Choices) == 0 {
+		return nil
+	}
+	return &r.Choices[0].Message
+}
+
+// StopReason returns the stop reason of the first choice, or StopUnknown.
+func (r *MessageResponse) StopReason() StopReason {
+	if r == nil || len(r.Choices) == 0 {
+		return StopUnknown
+	}
+	return StopReason(r.Choices[0].FinishReason)
+}
+
+// Text returns the text of the first choice, concatenating its text blocks.
+// It is empty for a response without choices.
+func (r *MessageResponse) Text() string {
+	message := r.message()
+	if message == nil {
+		return ""
+	}
+	if len(message.Blocks) == 0 {
+		return message.Content
+	}
+	var text string
+	for _, block := range message.Blocks {
+		if block.Type == TextBlock {
+			text += block.Text
+		}
+	}
+	return text
+}
+
+// ToolUses returns the tool_use blocks of the first choice.
+func (r *MessageResponse) ToolUses() []ContentBlock {
+	return r.blocks(ToolUseBlock)
+}
+
+// Thinking returns the text of the thinking blocks of the first choice.
+// Redacted thinking is left out.
+func (r *MessageResponse) Thinking() string {
+	var thinking string
+	for _, block := range r.blocks(ThinkingBlock) {
+		thinking += block.Thinking
+	}
+	return thinking
+}
+
+// Citations returns the citations of every text block of the first choice.
+func (r *MessageResponse) Citations() []Citation {
+	var citations []Citation
+	for _, block := range r.blocks(TextBlock) {
+		citations = append(citations, block.Citations...)
+	}
+	return citations
+}
+
+func (r *MessageResponse) blocks(blockType string) []ContentBlock {
+	message := r.message()
+	if message == nil {
+		return nil
+	}
+	var blocks []ContentBlock
+	for _, block := range message.Blocks {
+		if block.Type == blockType {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// ToAssistantMessage returns the first choice as an assistant message to
+// append to the conversation history, keeping every block, including tool
+// uses and thinking, so that the next request can continue from it.
+func (r *MessageResponse) ToAssistantMessage() Message {
+	message := r.message()
+	if message == nil {
+		return Message{Role: AssistantRole}
+	}
+	copied := *message
+	copied.Role = AssistantRole
+	copied.Blocks = append([]ContentBlock(nil), message.Blocks...)
+	return copied
+}
+
+// Clone returns a deep copy of r, so that changes to the copy's choices and
+// blocks do not affect r.
+func (r *MessageResponse) Clone() *MessageResponse {
+	if r == nil {
+		return nil
+	}
+	copied := *r
+	copied.Choices = append([]MessageChoice(nil), r.Choices...)
+	for i := range copied.Choices {
+		copied.Choices[i].Message = copied.Choices[i].Message.clone()
+	}
+	return &copied
+}
+
+func (m Message) clone() Message {
+	if m.Blocks == nil {
+		return m
+	}
+	m.Blocks = append([]ContentBlock(nil), m.Blocks...)
+	for i := range m.Blocks {
+		block := &m.Blocks[i]
+		if block.Input != nil {
+			block.Input = append(json.RawMessage(nil), block.Input...)
+		}
+		if block.Source != nil {
+			source := *block.Source
+			block.Source = &source
+		}
+		if block.CacheControl != nil {
+			cacheControl := *block.CacheControl
+			block.CacheControl = &cacheControl
+		}
+		block.Citations = append([]Citation(nil), block.Citations...)
+	}
+	return m
+}
@@ -0,0 +1,227 @@
+// pkg/models/validate.go
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxImageSize is the largest image, in bytes, that the API accepts.
+const MaxImageSize = 5 << 20
+
+// MinThinkingBudget is the smallest accepted thinking budget.
+const MinThinkingBudget = 1024
+
+// ImageMediaTypes lists the accepted image media types.
+var ImageMediaTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// MaxOutputTokens maps model families to the largest max_tokens they accept.
+// A model ID matches its family exactly or with a -YYYYMMDD date or -latest
+// suffix, so claude-3-5-sonnet-20241022 matches claude-3-5-sonnet but
+// claude-opus-4-5 does not match claude-opus-4.
+var MaxOutputTokens = map[string]int{
+	"claude-opus-4-5":   64000,
+	"claude-opus-4-1":   32000,
+	"claude-opus-4":     32000,
+	"claude-opus-4-0":   32000,
+	"claude-sonnet-4-5": 64000,
+	"claude-sonnet-4":   64000,
+	"claude-sonnet-4-0": 64000,
+	"claude-haiku-4-5":  64000,
+	"claude-3-7-sonnet": 64000,
+	"claude-3-5-sonnet": 8192,
+	"claude-3-5-haiku":  8192,
+	"claude-3-opus":     4096,
+	"claude-3-haiku":    4096,
+}
+
+// modelVersion matches the date or alias suffix of a model ID.
+var modelVersion = regexp.MustCompile(`-(\d{8}|latest)$`)
+
+// MaxOutputTokensFor returns the max_tokens limit of model. Models whose
+// family is not in MaxOutputTokens have no known limit.
+func MaxOutputTokensFor(model string) (int, bool) {
+	if limit, ok := MaxOutputTokens[model]; ok {
+		return limit, true
+	}
+	limit, ok := MaxOutputTokens[modelVersion.ReplaceAllString(model, "")]
+	return limit, ok
+}
+
+// ValidationError is a problem with one field of a request. Field is a path
+// in the JSON request body, e.g. messages[2].content[0].tool_use_id.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors lists every problem found by Validate.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	problems := make([]string, len(e))
+	for i, err := range e {
+		problems[i] = err.Error()
+	}
+	return "invalid request: " + strings.Join(problems, "; ")
+}
+
+// Validate checks r for mistakes that the API would reject with a 400 and
+// returns them all as ValidationErrors, or nil if none were found. Models
+// missing from MaxOutputTokens are not checked against a limit.
+func (r *MessageRequest) Validate() error {
+	var errs ValidationErrors
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if r.MaxTokens <= 0 {
+		add("max_tokens", "must be positive, got %d", r.MaxTokens)
+	} else if limit, ok := MaxOutputTokensFor(r.Model); ok && r.MaxTokens > limit {
+		add("max_tokens", "%d exceeds the limit of %d for %s", r.MaxTokens, limit, r.Model)
+	}
+	if r.Temperature < 0 || r.Temperature > 1 {
+		add("temperature", "must be between 0 and 1, got %g", r.Temperature)
+	}
+	if r.TopP < 0 || r.TopP > 1 {
+		add("top_p", "must be between 0 and 1, got %g", r.TopP)
+	}
+	if r.Thinking != nil && r.Thinking.Type == "enabled" {
+		switch budget := r.Thinking.BudgetTokens; {
+		case budget < MinThinkingBudget:
+			add("thinking.budget_tokens", "must be at least %d, got %d", MinThinkingBudget, budget)
+		case budget >= r.MaxTokens:
+			add("thinking.budget_tokens", "%d must be less than max_tokens %d", budget, r.MaxTokens)
+		}
+	}
+	tools := make(map[string]bool)
+	for i, tool := range r.Tools {
+		if tool.Name == "" {
+			add(fmt.Sprintf("tools[%d].name", i), "is required")
+		}
+		tools[tool.Name] = true
+	}
+	if choice := r.ToolChoice; choice != nil {
+		switch choice.Type {
+		case ToolChoiceAuto, ToolChoiceAny, ToolChoiceNone:
+		case ToolChoiceTool:
+			if !tools[choice.Name] {
+				add("tool_choice.name", "no tool named %q", choice.Name)
+			}
+		default:
+			add("tool_choice.type", "must be auto, any, tool or none, got %q", choice.Type)
+		}
+	}
+
+	if len(r.Messages) == 0 {
+		add("messages", "at least one message is required")
+	} else if r.Messages[0].Role != UserRole {
+		add("messages[0].role", "first message must be from the user, got %q", r.Messages[0].Role)
+	}
+	for i, message := range r.Messages {
+		field := fmt.Sprintf("messages[%d]", i)
+		switch message.Role {
+		case UserRole, AssistantRole:
+		case SystemRole:
+			add(field+".role", "system messages are not allowed; set System instead")
+		default:
+			add(field+".role", "must be user or assistant, got %q", message.Role)
+		}
+
+		if len(message.Blocks) == 0 {
+			if strings.TrimSpace(message.Content) == "" {
+				add(field+".content", "must not be empty")
+			}
+			continue
+		}
+		var uses map[string]bool
+		if i > 0 {
+			uses = toolUseIDs(r.Messages[i-1])
+		}
+		for j, block := range message.Blocks {
+			validateBlock(fmt.Sprintf("%s.content[%d]", field, j), message.Role, block, uses, add)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateBlock checks one content block of a message with role. uses holds
+// the tool_use IDs of the previous message.
+func validateBlock(field string, role MessageRoleType, block ContentBlock, uses map[string]bool, add func(field, format string, args ...interface{})) {
+	switch block.Type {
+	case TextBlock:
+		if strings.TrimSpace(block.Text) == "" {
+			add(field+".text", "must not be empty")
+		}
+	case ToolUseBlock:
+		if role != AssistantRole {
+			add(field, "tool_use blocks must be in assistant messages")
+		}
+		if block.ID == "" || block.Name == "" {
+			add(field, "tool_use blocks need an id and a name")
+		}
+	case ToolResultBlock:
+		if role != UserRole {
+			add(field, "tool_result blocks must be in user messages")
+		} else if !uses[block.ToolUseID] {
+			add(field+".tool_use_id", "no tool_use with id %q in the previous message", block.ToolUseID)
+		}
+	case ImageBlock:
+		validateImage(field+".source", block.Source, add)
+	case DocumentBlock:
+		if block.Source == nil {
+			add(field+".source", "is required")
+		}
+	case "":
+		add(field+".type", "is required")
+	}
+}
+
+func validateImage(field string, source *Source, add func(field, format string, args ...interface{})) {
+	if source == nil {
+		add(field, "is required")
+		return
+	}
+	switch source.Type {
+	case Base64Source:
+		supported := false
+		for _, mediaType := range ImageMediaTypes {
+			supported = supported || mediaType == source.MediaType
+		}
+		if !supported {
+			add(field+".media_type", "must be one of %s, got %q", strings.Join(ImageMediaTypes, ", "), source.MediaType)
+		}
+		if size := base64.StdEncoding.DecodedLen(len(source.Data)); size > MaxImageSize {
+			add(field+".data", "image of %d bytes exceeds the limit of %d", size, MaxImageSize)
+		} else if source.Data == "" {
+			add(field+".data", "must not be empty")
+		}
+	case URLSource:
+		if source.URL == "" {
+			add(field+".url", "must not be empty")
+		}
+	default:
+		add(field+".type", "must be %s or %s, got %q", Base64Source, URLSource, source.Type)
+	}
+}
+
+// toolUseIDs returns the IDs of the tool_use blocks of message.
+func toolUseIDs(message Message) map[string]bool {
+	ids := make(map[string]bool)
+	for _, block := range message.Blocks {
+		if block.Type == ToolUseBlock {
+			ids[block.ID] = true
+		}
+	}
+	return ids
+}
@@ -0,0 +1,356 @@
+// pkg/prompts/prompts.go
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// Labels set on calls made with a rendered Prompt. See Prompt.Options.
+const (
+	LabelName    = "prompt"
+	LabelVersion = "prompt_version"
+)
+
+// MissingVariablesError is returned by Render when variables referenced by
+// the template are not provided.
+type MissingVariablesError struct {
+	Template  string
+	Variables []string
+}
+
+func (e MissingVariablesError) Error() string {
+	return fmt.Sprintf("prompt %s: missing variables: %s", e.Template, strings.Join(e.Variables, ", "))
+}
+
+// Template renders a system prompt and message turns from variables of type
+// T, usually a struct or a map with string keys:
+//
+//	type Review struct {
+//		Language string
+//		Code     string
+//		Focus    string `prompt:"optional"`
+//	}
+//
+//	review := prompts.New[Review]("review", "v2").
+//		System("You review {{.Language}} code.").
+//		User(`{{.Code | tag "code"}}{{if .Focus}} Focus on {{.Focus}}.{{end}}`)
+//
+//	prompt, err := review.Render(Review{Language: "Go", Code: src})
+//
+// Templates use text/template syntax. Partials are shared by every part and
+// included with {{template "name" .}}. Besides the standard functions,
+// templates can call tag, escape, join and trim; see Funcs.
+//
+// Render fails with a MissingVariablesError unless every struct field the
+// template reads is non-zero, or every map key it reads is present. Struct
+// fields tagged `prompt:"optional"` may be left empty.
+//
+// The methods that add parts are not safe for concurrent use; Render is.
+type Template[T any] struct {
+	Name    string
+	Version string
+
+	set    *template.Template
+	system string
+	turns  []turn
+	errs   []error
+}
+
+type turn struct {
+	role models.MessageRoleType
+	name string
+}
+
+// Funcs are the functions available to every template.
+var Funcs = template.FuncMap{
+	// tag wraps value in an XML tag on its own lines: {{.Code | tag "code"}}.
+	"tag": func(name string, value interface{}) string {
+		return fmt.Sprintf("<%s>\n%v\n</%s>", name, value, name)
+	},
+	// escape escapes &, < and > so that text cannot close an enclosing tag.
+	"escape": func(value interface{}) string {
+		return xmlEscaper.Replace(fmt.Sprint(value))
+	},
+	// join joins elems with sep: {{.Items | join ", "}}.
+	"join": func(sep string, elems []string) string {
+		return strings.Join(elems, sep)
+	},
+	"trim": strings.TrimSpace,
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// New starts a template with the given name and version.
+func New[T any](name, version string) *Template[T] {
+	return &Template[T]{
+		Name:    name,
+		Version: version,
+		set:     template.New(name).Funcs(Funcs).Option("missingkey=error"),
+	}
+}
+
+// Partial defines a named template that other parts can include.
+func (t *Template[T]) Partial(name, text string) *Template[T] {
+	t.parse(name, text)
+	return t
+}
+
+// System sets the system prompt template.
+func (t *Template[T]) System(text string) *Template[T] {
+	t.system = t.Name + ".system"
+	t.parse(t.system, text)
+	return t
+}
+
+// User adds a user turn template.
+func (t *Template[T]) User(text string) *Template[T] {
+	return t.turn(models.UserRole, text)
+}
+
+// Assistant adds an assistant turn template. As the last turn it is a
+// prefill that the model continues.
+func (t *Template[T]) Assistant(text string) *Template[T] {
+	return t.turn(models.AssistantRole, text)
+}
+
+func (t *Template[T]) turn(role models.MessageRoleType, text string) *Template[T] {
+	name := fmt.Sprintf("%s.turn[%d]", t.Name, len(t.turns))
+	if t.parse(name, text) {
+		t.turns = append(t.turns, turn{role: role, name: name})
+	}
+	return t
+}
+
+func (t *Template[T]) parse(name, text string) bool {
+	if _, err := t.set.New(name).Parse(text); err != nil {
+		t.errs = append(t.errs, err)
+		return false
+	}
+	return true
+}
+
+// Variables returns the names of the variables the template reads, sorted.
+func (t *Template[T]) Variables() []string {
+	w := walker{set: t.set, vars: make(map[string]bool), seen: make(map[string]bool)}
+	names := make([]string, 0, len(t.turns)+1)
+	if t.system != "" {
+		names = append(names, t.system)
+	}
+	for _, turn := range t.turns {
+		names = append(names, turn.name)
+	}
+	for _, name := range names {
+		w.template(name, true)
+	}
+	vars := make([]string, 0, len(w.vars))
+	for name := range w.vars {
+		vars = append(vars, name)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// Render renders the template with vars. Surrounding whitespace is trimmed
+// from every part.
+func (t *Template[T]) Render(vars T) (*Prompt, error) {
+	if len(t.errs) > 0 {
+		return nil, fmt.Errorf("prompt %s: %w", t.Name, errors.Join(t.errs...))
+	}
+	if missing := missingVariables(vars, t.Variables()); len(missing) > 0 {
+		return nil, MissingVariablesError{Template: t.Name, Variables: missing}
+	}
+
+	prompt := &Prompt{Name: t.Name, Version: t.Version}
+	var err error
+	if t.system != "" {
+		if prompt.System, err = t.execute(t.system, vars); err != nil {
+			return nil, err
+		}
+	}
+	for _, turn := range t.turns {
+		text, err := t.execute(turn.name, vars)
+		if err != nil {
+			return nil, err
+		}
+		prompt.Messages = append(prompt.Messages, models.Message{Role: turn.role, Content: text})
+	}
+	return prompt, nil
+}
+
+func (t *Template[T]) execute(name string, vars T) (string, error) {
+	var b strings.Builder
+	if err := t.set.ExecuteTemplate(&b, name, vars); err != nil {
+		return "", fmt.Errorf("prompt %s: %w", t.Name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Prompt is a rendered Template.
+type Prompt struct {
+	Name     string
+	Version  string
+	System   string
+	Messages []models.Message
+}
+
+// Request returns a copy of params with the rendered system prompt, when
+// there is one, and the rendered turns appended to its messages.
+func (p *Prompt) Request(params models.MessageRequest) *models.MessageRequest {
+	req := params
+	if p.System != "" {
+		req.System = p.System
+	}
+	req.Messages = append(append([]models.Message(nil), params.Messages...), p.Messages...)
+	return &req
+}
+
+// Options returns request options that label calls with the name and
+// version of the prompt, so that they show up in the client's logs.
+func (p *Prompt) Options() []api.RequestOption {
+	return []api.RequestOption{
+		api.WithLabel(LabelName, p.Name),
+		api.WithLabel(LabelVersion, p.Version),
+	}
+}
+
+// walker collects the variables read by templates. A variable is a field
+// or key read from the top-level data, either through dot or through $.
+type walker struct {
+	set  *template.Template
+	vars map[string]bool
+	seen map[string]bool
+}
+
+// template walks the named template. root reports whether its dot is the
+// top-level data.
+func (w *walker) template(name string, root bool) {
+	key := fmt.Sprintf("%s/%t", name, root)
+	if w.seen[key] {
+		return
+	}
+	w.seen[key] = true
+	if tmpl := w.set.Lookup(name); tmpl != nil && tmpl.Tree != nil {
+		// Inside a template, $ is the data it was invoked with.
+		w.node(tmpl.Tree.Root, root, root)
+	}
+}
+
+func (w *walker) node(node parse.Node, dot, dollar bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.node(child, dot, dollar)
+		}
+	case *parse.ActionNode:
+		w.node(n.Pipe, dot, dollar)
+	case *parse.IfNode:
+		w.branch(&n.BranchNode, dot, dollar, dot)
+	case *parse.RangeNode:
+		w.branch(&n.BranchNode, dot, dollar, false)
+	case *parse.WithNode:
+		w.branch(&n.BranchNode, dot, dollar, false)
+	case *parse.TemplateNode:
+		w.node(n.Pipe, dot, dollar)
+		w.template(n.Name, n.Pipe != nil && w.isRoot(n.Pipe, dot, dollar))
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.node(cmd, dot, dollar)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			w.node(arg, dot, dollar)
+		}
+	case *parse.ChainNode:
+		w.node(n.Node, dot, dollar)
+	case *parse.FieldNode:
+		if dot {
+			w.vars[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if dollar && n.Ident[0] == "$" && len(n.Ident) > 1 {
+			w.vars[n.Ident[1]] = true
+		}
+	}
+}
+
+// branch walks an if, range or with node. The dot of its list is inner; the
+// else list keeps the outer dot.
+func (w *walker) branch(n *parse.BranchNode, dot, dollar, inner bool) {
+	w.node(n.Pipe, dot, dollar)
+	w.node(n.List, inner, dollar)
+	if n.ElseList != nil {
+		w.node(n.ElseList, dot, dollar)
+	}
+}
+
+// isRoot reports whether pipe evaluates to the top-level data.
+func (w *walker) isRoot(pipe *parse.PipeNode, dot, dollar bool) bool {
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.VariableNode:
+		return dollar && len(arg.Ident) == 1 && arg.Ident[0] == "$"
+	}
+	return false
+}
+
+// missingVariables returns the names that vars does not provide.
+func missingVariables(vars interface{}, names []string) []string {
+	v := reflect.ValueOf(vars)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return names
+		}
+		v = v.Elem()
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !provided(v, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func provided(v reflect.Value, name string) bool {
+	if v.MethodByName(name).IsValid() || (v.CanAddr() && v.Addr().MethodByName(name).IsValid()) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := v.Type().FieldByName(name)
+		if !ok {
+			return false
+		}
+		if field.Tag.Get("prompt") == "optional" {
+			return true
+		}
+		value, err := v.FieldByIndexErr(field.Index)
+		return err == nil && !value.IsZero()
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return true
+		}
+		return v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())).IsValid()
+	}
+	return true
+}
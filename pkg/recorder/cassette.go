@@ -0,0 +1,97 @@
+// pkg/recorder/cassette.go
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a response body together with the time that passed
+// since the previous chunk, so that streams can be replayed with their timing.
+// Data holds text; bytes that are not valid UTF-8 are kept in Raw instead,
+// which JSON encodes as base64.
+type Chunk struct {
+	Delay time.Duration `json:"delay"`
+	Data  string        `json:"data,omitempty"`
+	Raw   []byte        `json:"raw,omitempty"`
+}
+
+func newChunk(delay time.Duration, data []byte) Chunk {
+	if utf8.Valid(data) {
+		return Chunk{Delay: delay, Data: string(data)}
+	}
+	return Chunk{Delay: delay, Raw: append([]byte(nil), data...)}
+}
+
+func (c Chunk) bytes() []byte {
+	if c.Raw != nil {
+		return c.Raw
+	}
+	return []byte(c.Data)
+}
+
+// RecordedRequest is the request half of an Interaction.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response half of an Interaction. Body is split into
+// the chunks in which it arrived.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Chunks     []Chunk     `json:"chunks,omitempty"`
+}
+
+// Body returns the full response body.
+func (r *RecordedResponse) Body() string {
+	var body []byte
+	for _, chunk := range r.Chunks {
+		body = append(body, chunk.bytes()...)
+	}
+	return string(body)
+}
+
+// Interaction is one recorded request/response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette is the fixture file format holding a sequence of interactions.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette from path.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to path, creating parent directories as needed.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
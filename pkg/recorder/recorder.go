@@ -0,0 +1,379 @@
+// pkg/recorder/recorder.go
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Mode selects whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay serves every request from the cassette and fails on misses.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the network and records it.
+	ModeRecord
+	// ModeReplayOrRecord replays if the cassette file exists and records otherwise.
+	ModeReplayOrRecord
+)
+
+// Redacted replaces scrubbed header and field values.
+const Redacted = "[REDACTED]"
+
+// DefaultScrubHeaders are the headers always removed from recorded requests.
+var DefaultScrubHeaders = []string{"X-Api-Key", "Authorization", "Anthropic-Api-Key", "Cookie"}
+
+// Matcher reports whether an incoming request matches a recorded one. body is
+// the incoming request body after field scrubbing.
+type Matcher func(req *http.Request, body []byte, recorded *RecordedRequest) bool
+
+// StrictMatcher requires the method, full URL and JSON body to be equal.
+func StrictMatcher(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return req.Method == recorded.Method &&
+		req.URL.String() == recorded.URL &&
+		equalJSON(body, []byte(recorded.Body))
+}
+
+// LenientMatcher requires only the method and URL path to be equal.
+func LenientMatcher(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	if req.Method != recorded.Method {
+		return false
+	}
+	u, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	return u.Path == req.URL.Path
+}
+
+// Options configures a Recorder.
+type Options struct {
+	Mode Mode
+	// Lenient lets requests match any unused interaction with LenientMatcher.
+	// By default matching is strict: each request must exactly match the
+	// next interaction in recorded order.
+	Lenient bool
+	// Matcher overrides the matcher selected by Lenient.
+	Matcher Matcher
+	// Transport sends requests while recording. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// ScrubHeaders are removed from recorded requests in addition to DefaultScrubHeaders.
+	ScrubHeaders []string
+	// ScrubFields are JSON fields of request bodies whose values are replaced
+	// with Redacted, e.g. "user_id".
+	ScrubFields []string
+	// Scrub is called on every interaction before the cassette is saved.
+	Scrub func(*Interaction)
+	// RealTime replays response chunks with their recorded delays.
+	RealTime bool
+}
+
+// Recorder is an http.RoundTripper that records interactions to a cassette
+// file or replays them from it.
+type Recorder struct {
+	path      string
+	opts      Options
+	recording bool
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// New creates a Recorder for the cassette at path.
+func New(path string, opts Options) (*Recorder, error) {
+	if opts.Matcher == nil {
+		opts.Matcher = StrictMatcher
+		if opts.Lenient {
+			opts.Matcher = LenientMatcher
+		}
+	}
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
+	r := &Recorder{path: path, opts: opts, cassette: &Cassette{}}
+
+	recording := opts.Mode == ModeRecord
+	if opts.Mode == ModeReplayOrRecord {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			recording = true
+		}
+	}
+	r.recording = recording
+	if !recording {
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+		r.used = make([]bool, len(cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Recording reports whether the recorder sends requests to the network.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// HTTPClient returns an http.Client that uses the recorder as its transport.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if r.recording {
+		return r.record(req, body)
+	}
+	return r.replay(req, r.scrubBody(body))
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		if r.opts.Matcher(req, body, &interaction.Request) {
+			r.used[i] = true
+			return &http.Response{
+				StatusCode: interaction.Response.StatusCode,
+				Status:     fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     interaction.Response.Header.Clone(),
+				Body:       &replayBody{chunks: interaction.Response.Chunks, realTime: r.opts.RealTime},
+				Request:    req,
+			}, nil
+		}
+		// Strict matching replays interactions in order.
+		if !r.opts.Lenient {
+			break
+		}
+	}
+	return nil, fmt.Errorf("no recorded interaction matches %s %s", req.Method, req.URL)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.opts.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   string(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	resp.Body = &recordingBody{body: resp.Body, recorder: r, interaction: interaction, last: time.Now()}
+	return resp, nil
+}
+
+// Stop saves the cassette when recording. Response bodies that have not been
+// fully read are saved as far as they were read.
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scrubbed := &Cassette{}
+	for _, interaction := range r.cassette.Interactions {
+		copied := *interaction
+		copied.Request.Header = interaction.Request.Header.Clone()
+		copied.Response.Chunks = append([]Chunk(nil), interaction.Response.Chunks...)
+		r.scrub(&copied)
+		scrubbed.Interactions = append(scrubbed.Interactions, &copied)
+	}
+	return scrubbed.Save(r.path)
+}
+
+func (r *Recorder) scrub(interaction *Interaction) {
+	for _, name := range append(append([]string(nil), DefaultScrubHeaders...), r.opts.ScrubHeaders...) {
+		if interaction.Request.Header.Get(name) != "" {
+			interaction.Request.Header.Set(name, Redacted)
+		}
+	}
+	interaction.Request.Body = string(r.scrubBody([]byte(interaction.Request.Body)))
+	if r.opts.Scrub != nil {
+		r.opts.Scrub(interaction)
+	}
+}
+
+// scrubBody replaces the values of ScrubFields anywhere in a JSON body.
+func (r *Recorder) scrubBody(body []byte) []byte {
+	if len(r.opts.ScrubFields) == 0 || len(body) == 0 {
+		return body
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	fields := make(map[string]bool, len(r.opts.ScrubFields))
+	for _, field := range r.opts.ScrubFields {
+		fields[field] = true
+	}
+	scrubbed, err := json.Marshal(scrubValue(v, fields))
+	if err != nil {
+		return body
+	}
+	return scrubbed
+}
+
+func scrubValue(v interface{}, fields map[string]bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, field := range value {
+			if fields[k] {
+				value[k] = Redacted
+			} else {
+				value[k] = scrubValue(field, fields)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = scrubValue(item, fields)
+		}
+	}
+	return v
+}
+
+func equalJSON(a, b []byte) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	na, _ := json.Marshal(va)
+	nb, _ := json.Marshal(vb)
+	return bytes.Equal(na, nb)
+}
+
+// recordingBody records the chunks of a response body as they are read.
+type recordingBody struct {
+	body        io.ReadCloser
+	recorder    *Recorder
+	interaction *Interaction
+	last        time.Time
+	// partial holds the start of a UTF-8 sequence cut off by a read, which
+	// is recorded with the rest of the sequence.
+	partial []byte
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		data := append(b.partial, p[:n]...)
+		b.partial = nil
+		if err == nil {
+			data, b.partial = splitIncompleteRune(data)
+		}
+		b.record(data)
+	}
+	if err != nil {
+		b.flush()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.flush()
+	return b.body.Close()
+}
+
+func (b *recordingBody) flush() {
+	if len(b.partial) > 0 {
+		b.record(b.partial)
+		b.partial = nil
+	}
+}
+
+func (b *recordingBody) record(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	now := time.Now()
+	b.recorder.mu.Lock()
+	b.interaction.Response.Chunks = append(b.interaction.Response.Chunks, newChunk(now.Sub(b.last), data))
+	b.recorder.mu.Unlock()
+	b.last = now
+}
+
+// splitIncompleteRune splits an incomplete UTF-8 sequence off the end of
+// data.
+func splitIncompleteRune(data []byte) (complete, rest []byte) {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i], append([]byte(nil), data[i:]...)
+			}
+			break
+		}
+	}
+	return data, nil
+}
+
+// replayBody serves recorded chunks, optionally waiting out their delays.
+type replayBody struct {
+	chunks   []Chunk
+	realTime bool
+	pending  []byte
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if len(b.chunks) == 0 {
+			return 0, io.EOF
+		}
+		chunk := b.chunks[0]
+		b.chunks = b.chunks[1:]
+		if b.realTime && chunk.Delay > 0 {
+			time.Sleep(chunk.Delay)
+		}
+		b.pending = chunk.bytes()
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *replayBody) Close() error {
+	return nil
+}
@@ -0,0 +1,82 @@
+// pkg/streams/events.go
+package streams
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// MessageEventConverter decodes the data of a message stream into typed events.
+// Events named after their type, such as content_block_delta, are decoded as well
+// as unnamed data events.
+func MessageEventConverter(inputCh <-chan DataEvent) (<-chan models.StreamEvent, <-chan error) {
+	outputCh := make(chan models.StreamEvent)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(outputCh)
+		defer close(errCh)
+
+		for event := range inputCh {
+			switch event.Event {
+			case "error":
+				errCh <- errors.New(string(event.Data))
+			default:
+				if len(event.Data) == 0 {
+					continue
+				}
+				var streamEvent models.StreamEvent
+				if err := json.Unmarshal(event.Data, &streamEvent); err != nil {
+					errCh <- err
+				} else {
+					outputCh <- streamEvent
+				}
+			}
+		}
+	}()
+
+	return outputCh, errCh
+}
+
+// ToolInputAccumulator collects the input_json_delta fragments of every
+// tool_use block in a stream, keyed by content block index.
+type ToolInputAccumulator struct {
+	inputs map[int]*PartialJSON
+}
+
+// NewToolInputAccumulator creates an empty accumulator.
+func NewToolInputAccumulator() *ToolInputAccumulator {
+	return &ToolInputAccumulator{inputs: make(map[int]*PartialJSON)}
+}
+
+// Add feeds a stream event to the accumulator. It reports whether the event
+// updated the input of a tool_use block.
+func (a *ToolInputAccumulator) Add(event models.StreamEvent) bool {
+	switch event.Type {
+	case models.ContentBlockStartEvent:
+		if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+			return false
+		}
+		a.inputs[event.Index] = &PartialJSON{}
+		return true
+	case models.ContentBlockDeltaEvent:
+		if event.Delta == nil || event.Delta.Type != models.InputJSONDelta {
+			return false
+		}
+		input, ok := a.inputs[event.Index]
+		if !ok {
+			input = &PartialJSON{}
+			a.inputs[event.Index] = input
+		}
+		input.Append(event.Delta.PartialJSON)
+		return true
+	}
+	return false
+}
+
+// Input returns the accumulated input of the tool_use block at index, or nil.
+func (a *ToolInputAccumulator) Input(index int) *PartialJSON {
+	return a.inputs[index]
+}
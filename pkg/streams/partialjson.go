@@ -0,0 +1,305 @@
+// pkg/streams/partialjson.go
+package streams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// partialFrame is an open object or array in a partial JSON document.
+type partialFrame struct {
+	kind byte
+	// safe is the output length at which the container can be closed
+	// and still form valid JSON.
+	safe int
+}
+
+// CompletePartialJSON turns a possibly truncated JSON document into a valid one
+// by dropping incomplete keys and values and closing open strings, arrays and
+// objects. An empty or whitespace-only input yields a nil slice.
+func CompletePartialJSON(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data)+8)
+	var stack []partialFrame
+	// expectValue is true whenever the next token must be a value.
+	expectValue := true
+	// expectKey is true when the next token in an object must be a key.
+	expectKey := false
+	done := false
+
+	finish := func() []byte {
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if expectValue || expectKey {
+				out = out[:top.safe]
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].kind == '{' {
+					out = append(out, '}')
+				} else {
+					out = append(out, ']')
+				}
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	}
+
+	afterValue := func() {
+		expectValue = false
+		expectKey = false
+		if len(stack) == 0 {
+			done = true
+			return
+		}
+		stack[len(stack)-1].safe = len(out)
+	}
+
+	i := 0
+	for i < len(data) {
+		c := data[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			i++
+			continue
+		}
+		if done {
+			return nil, fmt.Errorf("unexpected %q after top-level value at offset %d", c, i)
+		}
+
+		switch {
+		case expectKey:
+			switch c {
+			case '}':
+				stack = stack[:len(stack)-1]
+				out = append(out, '}')
+				i++
+				afterValue()
+			case '"':
+				end, ok := scanJSONString(data, i)
+				if !ok {
+					return finish(), nil
+				}
+				j := skipJSONSpace(data, end)
+				if j >= len(data) {
+					return finish(), nil
+				}
+				if data[j] != ':' {
+					return nil, fmt.Errorf("expected ':' after object key at offset %d", j)
+				}
+				out = append(out, data[i:end]...)
+				out = append(out, ':')
+				i = j + 1
+				expectKey = false
+				expectValue = true
+			default:
+				return nil, fmt.Errorf("unexpected %q where object key expected at offset %d", c, i)
+			}
+
+		case expectValue:
+			switch {
+			case c == '{' || c == '[':
+				out = append(out, c)
+				stack = append(stack, partialFrame{kind: c, safe: len(out)})
+				i++
+				if c == '{' {
+					expectKey = true
+					expectValue = false
+				}
+			case c == ']' && len(stack) > 0 && stack[len(stack)-1].kind == '[' && stack[len(stack)-1].safe == len(out):
+				stack = stack[:len(stack)-1]
+				out = append(out, ']')
+				i++
+				afterValue()
+			case c == '"':
+				end, ok := scanJSONString(data, i)
+				if !ok {
+					out = append(out, trimPartialString(data[i:])...)
+					out = append(out, '"')
+					afterValue()
+					return finish(), nil
+				}
+				out = append(out, data[i:end]...)
+				i = end
+				afterValue()
+			case c == '-' || (c >= '0' && c <= '9'):
+				end := i
+				for end < len(data) && bytes.IndexByte([]byte("+-.0123456789eE"), data[end]) >= 0 {
+					end++
+				}
+				if end == len(data) {
+					num := bytes.TrimRight(data[i:end], "+-.eE")
+					if len(num) == 0 {
+						return finish(), nil
+					}
+					out = append(out, num...)
+					afterValue()
+					return finish(), nil
+				}
+				out = append(out, data[i:end]...)
+				i = end
+				afterValue()
+			case c == 't' || c == 'f' || c == 'n':
+				end := i
+				for end < len(data) && data[end] >= 'a' && data[end] <= 'z' {
+					end++
+				}
+				word := data[i:end]
+				var literal string
+				for _, l := range []string{"true", "false", "null"} {
+					if bytes.HasPrefix([]byte(l), word) {
+						literal = l
+					}
+				}
+				if literal == "" || (end < len(data) && len(word) != len(literal)) {
+					return nil, fmt.Errorf("invalid literal %q at offset %d", word, i)
+				}
+				out = append(out, literal...)
+				i = end
+				afterValue()
+			default:
+				return nil, fmt.Errorf("unexpected %q where value expected at offset %d", c, i)
+			}
+
+		default:
+			top := stack[len(stack)-1]
+			switch {
+			case c == ',':
+				out = append(out, ',')
+				i++
+				if top.kind == '{' {
+					expectKey = true
+				} else {
+					expectValue = true
+				}
+			case (c == '}' && top.kind == '{') || (c == ']' && top.kind == '['):
+				stack = stack[:len(stack)-1]
+				out = append(out, c)
+				i++
+				afterValue()
+			default:
+				return nil, fmt.Errorf("unexpected %q after value at offset %d", c, i)
+			}
+		}
+	}
+
+	return finish(), nil
+}
+
+// ParsePartialJSON parses a possibly truncated JSON document into its
+// best-effort value. It returns nil for an empty document.
+func ParsePartialJSON(data []byte) (interface{}, error) {
+	completed, err := CompletePartialJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if completed == nil {
+		return nil, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(completed, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse partial JSON: %w", err)
+	}
+	return v, nil
+}
+
+// UnmarshalPartialJSON decodes a possibly truncated JSON document into v.
+// Fields whose values have not arrived yet are left untouched.
+func UnmarshalPartialJSON(data []byte, v interface{}) error {
+	completed, err := CompletePartialJSON(data)
+	if err != nil {
+		return err
+	}
+	if completed == nil {
+		return nil
+	}
+	if err := json.Unmarshal(completed, v); err != nil {
+		return fmt.Errorf("failed to decode partial JSON: %w", err)
+	}
+	return nil
+}
+
+// scanJSONString returns the offset just past the string starting at data[start]
+// and whether the closing quote was found.
+func scanJSONString(data []byte, start int) (int, bool) {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, true
+		}
+	}
+	return len(data), false
+}
+
+// trimPartialString drops a trailing incomplete escape sequence or UTF-8 rune
+// from an unterminated string so that it can be closed with a quote.
+func trimPartialString(s []byte) []byte {
+	for i := len(s) - 1; i > 0 && i >= len(s)-6; i-- {
+		if s[i] != '\\' {
+			continue
+		}
+		// Count the run of backslashes to tell an escape from an escaped backslash.
+		n := 0
+		for j := i; j > 0 && s[j] == '\\'; j-- {
+			n++
+		}
+		if n%2 == 0 {
+			break
+		}
+		rest := s[i+1:]
+		if len(rest) == 0 || (rest[0] == 'u' && len(rest) < 5) {
+			s = s[:i]
+		}
+		break
+	}
+	for len(s) > 1 && !utf8.FullRune(s[lastRuneStart(s):]) {
+		s = s[:lastRuneStart(s)]
+	}
+	return s
+}
+
+// lastRuneStart returns the offset of the first byte of the last rune in s.
+func lastRuneStart(s []byte) int {
+	i := len(s) - 1
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// PartialJSON accumulates input_json_delta fragments of a single tool_use
+// block and exposes the best-effort value parsed so far.
+type PartialJSON struct {
+	buf bytes.Buffer
+}
+
+// Append adds the next fragment to the buffer.
+func (p *PartialJSON) Append(fragment string) {
+	p.buf.WriteString(fragment)
+}
+
+// Bytes returns the raw fragments received so far.
+func (p *PartialJSON) Bytes() []byte {
+	return p.buf.Bytes()
+}
+
+// Value returns the best-effort value of the fragments received so far.
+func (p *PartialJSON) Value() (interface{}, error) {
+	return ParsePartialJSON(p.buf.Bytes())
+}
+
+// Decode decodes the fragments received so far into v.
+func (p *PartialJSON) Decode(v interface{}) error {
+	return UnmarshalPartialJSON(p.buf.Bytes(), v)
+}
@@ -6,6 +6,8 @@ import (
     "encoding/json"
     "errors"
     "io"
+
+    "github.com/Aanthord/go-anthropic/pkg/models"
 )
 
 type DataEvent struct {
@@ -21,9 +23,18 @@ func ConsumeStream(stream io.ReadCloser) <-chan DataEvent {
         defer close(outputCh)
 
         scanner := bufio.NewScanner(stream)
+        eventName := ""
 
         for scanner.Scan() {
             line := scanner.Bytes()
+            // A blank line ends an event; a line starting with ':' is a comment.
+            if len(line) == 0 {
+                eventName = ""
+                continue
+            }
+            if line[0] == ':' {
+                continue
+            }
             parts := bytes.SplitN(line, []byte(": "), 2)
 
             if len(parts) < 2 {
@@ -34,18 +45,20 @@ func ConsumeStream(stream io.ReadCloser) <-chan DataEvent {
                 continue
             }
 
-            field, value := string(parts[0]), parts[1]
+            field, value := string(parts[0]), append([]byte(nil), parts[1]...)
 
             switch field {
             case "data":  
+                event := "data"
+                if eventName != "" {
+                    event = eventName
+                }
                 outputCh <- DataEvent{
-                    Event: "data",
+                    Event: event,
                     Data:  value,
                 }
             case "event":
-                outputCh <- DataEvent{
-                    Event: string(value),
-                }
+                eventName = string(value)
             default:
                 outputCh <- DataEvent{
                     Event: "error", 
@@ -65,8 +78,10 @@ func ConsumeStream(stream io.ReadCloser) <-chan DataEvent {
     return outputCh
 }
 
-func CompletionStreamConverter(inputCh <-chan DataEvent) (<-chan CompletionResponse, <-chan error) {
-    outputCh := make(chan CompletionResponse)
+// CompletionStreamConverter decodes the data of every event except error and
+// ping events, whether it is unnamed or named after its type.
+func CompletionStreamConverter(inputCh <-chan DataEvent) (<-chan models.CompletionResponse, <-chan error) {
+    outputCh := make(chan models.CompletionResponse)
     errCh := make(chan error)
 
     go func() {
@@ -77,8 +92,12 @@ func CompletionStreamConverter(inputCh <-chan DataEvent) (<-chan CompletionRespo
             switch event.Event {
             case "error":
                 errCh <- errors.New(string(event.Data)) 
-            case "data":
-                var completion CompletionResponse
+            case "ping":
+            default:
+                if len(event.Data) == 0 {
+                    continue
+                }
+                var completion models.CompletionResponse
                 if err := json.Unmarshal(event.Data, &completion); err != nil {
                     errCh <- err
                 } else {
@@ -91,8 +110,11 @@ func CompletionStreamConverter(inputCh <-chan DataEvent) (<-chan CompletionRespo
     return outputCh, errCh
 }
 
-func MessageStreamConverter(inputCh <-chan DataEvent) (<-chan MessageResponse, <-chan error) {
-    outputCh := make(chan MessageResponse)
+// MessageStreamConverter decodes the data of every event except error and
+// ping events, whether it is unnamed or named after its type, such as
+// content_block_delta.
+func MessageStreamConverter(inputCh <-chan DataEvent) (<-chan models.MessageResponse, <-chan error) {
+    outputCh := make(chan models.MessageResponse)
     errCh := make(chan error) 
 
     go func() {
@@ -103,8 +125,12 @@ func MessageStreamConverter(inputCh <-chan DataEvent) (<-chan MessageResponse, <
             switch event.Event {
             case "error":
                 errCh <- errors.New(string(event.Data))
-            case "data":  
-                var message MessageResponse
+            case "ping":
+            default:
+                if len(event.Data) == 0 {
+                    continue
+                }
+                var message models.MessageResponse
                 if err := json.Unmarshal(event.Data, &message); err != nil {
                     errCh <- err  
                 } else {
@@ -0,0 +1,140 @@
+// pkg/streams/synthetic.go
+package streams
+
+import (
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// MessageChunks splits a complete response into the chunks a stream of it
+// would carry: the text of each choice in pieces of at most chunkSize runes
+// (or whole when chunkSize is zero), one chunk per non-text content block, and
+// a final chunk with the finish reasons and usage.
+func MessageChunks(resp *models.MessageResponse, chunkSize int) []models.MessageResponse {
+	header := func() models.MessageResponse {
+		return models.MessageResponse{ID: resp.ID, Object: resp.Object, Created: resp.Created, Model: resp.Model}
+	}
+	var chunks []models.MessageResponse
+
+	for _, choice := range resp.Choices {
+		for _, piece := range splitText(choice.Message.Content, chunkSize) {
+			chunk := header()
+			chunk.Choices = []models.MessageChoice{{
+				Index:   choice.Index,
+				Message: models.Message{Role: choice.Message.Role, Content: piece},
+			}}
+			chunks = append(chunks, chunk)
+		}
+		for _, block := range choice.Message.Blocks {
+			if block.Type == models.TextBlock {
+				continue
+			}
+			chunk := header()
+			chunk.Choices = []models.MessageChoice{{
+				Index:   choice.Index,
+				Message: models.Message{Role: choice.Message.Role, Blocks: []models.ContentBlock{block}},
+			}}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	final := header()
+	final.Usage = resp.Usage
+	for _, choice := range resp.Choices {
+		final.Choices = append(final.Choices, models.MessageChoice{Index: choice.Index, FinishReason: choice.FinishReason})
+	}
+	return append(chunks, final)
+}
+
+// SyntheticStream returns closed channels that deliver chunks in the shape
+// returned by the streaming client methods.
+func SyntheticStream(chunks []models.MessageResponse) (<-chan models.MessageResponse, <-chan error) {
+	outputCh := make(chan models.MessageResponse, len(chunks))
+	for _, chunk := range chunks {
+		outputCh <- chunk
+	}
+	close(outputCh)
+	errCh := make(chan error)
+	close(errCh)
+	return outputCh, errCh
+}
+
+// AccumulateChunk folds a stream chunk into the response assembled so far,
+// appending text and content blocks per choice. It is the inverse of MessageChunks.
+func AccumulateChunk(assembled *models.MessageResponse, chunk models.MessageResponse) {
+	if chunk.ID != "" {
+		assembled.ID = chunk.ID
+	}
+	if chunk.Object != "" {
+		assembled.Object = chunk.Object
+	}
+	if chunk.Created != 0 {
+		assembled.Created = chunk.Created
+	}
+	if chunk.Model != "" {
+		assembled.Model = chunk.Model
+	}
+	if chunk.Usage != (models.MessageUsage{}) {
+		assembled.Usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		for len(assembled.Choices) <= choice.Index {
+			assembled.Choices = append(assembled.Choices, models.MessageChoice{Index: len(assembled.Choices)})
+		}
+		target := &assembled.Choices[choice.Index]
+		if choice.Message.Role != "" {
+			target.Message.Role = choice.Message.Role
+		}
+		appendContent(&target.Message, choice.Message)
+		if choice.FinishReason != "" {
+			target.FinishReason = choice.FinishReason
+		}
+	}
+}
+
+// appendContent appends the text and blocks of piece to message, keeping the
+// text blocks of message in step with its Content once it has blocks.
+func appendContent(message *models.Message, piece models.Message) {
+	if len(piece.Blocks) == 0 {
+		if piece.Content == "" {
+			return
+		}
+		message.Content += piece.Content
+		if n := len(message.Blocks); n > 0 {
+			if message.Blocks[n-1].Type == models.TextBlock {
+				message.Blocks[n-1].Text += piece.Content
+			} else {
+				message.Blocks = append(message.Blocks, models.ContentBlock{Type: models.TextBlock, Text: piece.Content})
+			}
+		}
+		return
+	}
+	if len(message.Blocks) == 0 && message.Content != "" {
+		message.Blocks = []models.ContentBlock{{Type: models.TextBlock, Text: message.Content}}
+	}
+	for _, block := range piece.Blocks {
+		if block.Type == models.TextBlock {
+			message.Content += block.Text
+		}
+		message.Blocks = append(message.Blocks, block)
+	}
+}
+
+func splitText(text string, size int) []string {
+	if text == "" {
+		return nil
+	}
+	runes := []rune(text)
+	if size <= 0 || len(runes) <= size {
+		return []string{text}
+	}
+	var pieces []string
+	for len(runes) > 0 {
+		n := size
+		if n > len(runes) {
+			n = len(runes)
+		}
+		pieces = append(pieces, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return pieces
+}
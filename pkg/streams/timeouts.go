@@ -0,0 +1,54 @@
+// pkg/streams/timeouts.go
+package streams
+
+import (
+	"fmt"
+	"time"
+)
+
+// WatchStream forwards events from input and reports an error event if the
+// first event does not arrive within firstEvent or if the stream then stays
+// silent for longer than idle. Every event, including ping events, resets the
+// idle timer. A zero duration disables that limit. On timeout it calls cancel
+// to abort the underlying request and discards whatever input still arrives;
+// cancel is also called once input ends.
+func WatchStream(input <-chan DataEvent, firstEvent, idle time.Duration, cancel func()) <-chan DataEvent {
+	output := make(chan DataEvent)
+
+	go func() {
+		defer close(output)
+		defer cancel()
+
+		limit, phase := firstEvent, "first event"
+		for {
+			var timer *time.Timer
+			var timeout <-chan time.Time
+			if limit > 0 {
+				timer = time.NewTimer(limit)
+				timeout = timer.C
+			}
+			select {
+			case event, ok := <-input:
+				if timer != nil {
+					timer.Stop()
+				}
+				if !ok {
+					return
+				}
+				output <- event
+				limit, phase = idle, "idle"
+			case <-timeout:
+				output <- DataEvent{
+					Event: "error",
+					Data:  []byte(fmt.Sprintf("stream %s timeout: no event within %s", phase, limit)),
+				}
+				cancel()
+				for range input {
+				}
+				return
+			}
+		}
+	}()
+
+	return output
+}
@@ -0,0 +1,185 @@
+// pkg/tokens/calibrate.go
+package tokens
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// ErrNoUsage is returned by ParseSample when the response reports no input
+// tokens.
+var ErrNoUsage = errors.New("response has no input token usage")
+
+// Sample pairs the features of a request with the input tokens the API
+// reported for it.
+type Sample struct {
+	Features    Features
+	InputTokens int
+}
+
+// ParseSample builds a Sample from a recorded request body and the body of
+// its response, which may be JSON or a server-sent event stream. Cached
+// input tokens count towards the input.
+func ParseSample(requestBody, responseBody []byte) (Sample, error) {
+	var req models.MessageRequest
+	if err := json.Unmarshal(requestBody, &req); err != nil {
+		return Sample{}, fmt.Errorf("failed to decode request: %w", err)
+	}
+	tokens := responseInputTokens(responseBody)
+	if tokens == 0 {
+		return Sample{}, ErrNoUsage
+	}
+	return Sample{Features: Measure(&req), InputTokens: tokens}, nil
+}
+
+// usage covers the field names of both the API and models.MessageUsage.
+type usage struct {
+	InputTokens              int `json:"input_tokens"`
+	PromptTokens             int `json:"prompt_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (u *usage) total() int {
+	if u == nil {
+		return 0
+	}
+	return max(u.InputTokens, u.PromptTokens) + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// usageEvent matches a response body as well as the message_start event of
+// a stream.
+type usageEvent struct {
+	Usage   *usage `json:"usage"`
+	Message *struct {
+		Usage *usage `json:"usage"`
+	} `json:"message"`
+}
+
+func (e usageEvent) total() int {
+	n := e.Usage.total()
+	if e.Message != nil {
+		n = max(n, e.Message.Usage.total())
+	}
+	return n
+}
+
+func responseInputTokens(body []byte) int {
+	var event usageEvent
+	if json.Unmarshal(body, &event) == nil {
+		return event.total()
+	}
+	tokens := 0
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		event = usageEvent{}
+		if json.Unmarshal([]byte(strings.TrimSpace(data)), &event) == nil {
+			tokens = max(tokens, event.total())
+		}
+	}
+	return tokens
+}
+
+// Ridge is how strongly Fit pulls factors towards the prior, relative to the
+// weight of the samples. It keeps factors of features that the samples do
+// not exercise, or cannot tell apart, at their prior values.
+const Ridge = 0.01
+
+// Fit returns the factors that best predict the input tokens of samples in
+// the least-squares sense, regularized towards prior. Factors of features
+// that no sample has keep their prior values.
+func Fit(samples []Sample, prior Factors) (Factors, error) {
+	if len(samples) == 0 {
+		return prior, errors.New("no samples to fit")
+	}
+	p := prior.vector()
+	n := len(p)
+
+	// Solve (XᵀX + D) f = Xᵀy + D p, where D scales Ridge by the weight of
+	// each feature so that the result does not depend on feature units.
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n+1)
+	}
+	for _, s := range samples {
+		x := s.Features.vector()
+		for i := range x {
+			for j := range x {
+				a[i][j] += x[i] * x[j]
+			}
+			a[i][n] += x[i] * float64(s.InputTokens)
+		}
+	}
+	for i := range a {
+		d := Ridge * a[i][i]
+		if d == 0 {
+			d = 1
+		}
+		a[i][i] += d
+		a[i][n] += d * p[i]
+	}
+
+	f, err := solve(a)
+	if err != nil {
+		return prior, err
+	}
+	return factorsOf(f), nil
+}
+
+// solve solves the augmented linear system a by Gaussian elimination with
+// partial pivoting.
+func solve(a [][]float64) ([]float64, error) {
+	n := len(a)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil, errors.New("samples do not determine the factors")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for row := col + 1; row < n; row++ {
+			k := a[row][col] / a[col][col]
+			for j := col; j <= n; j++ {
+				a[row][j] -= k * a[col][j]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := a[row][n]
+		for j := row + 1; j < n; j++ {
+			sum -= a[row][j] * x[j]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x, nil
+}
+
+// MeanError returns the mean absolute relative error of factors on samples,
+// e.g. 0.05 for estimates that are off by 5% on average.
+func MeanError(samples []Sample, factors Factors) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += math.Abs(factors.Apply(s.Features)-float64(s.InputTokens)) / float64(s.InputTokens)
+	}
+	return sum / float64(len(samples))
+}
@@ -0,0 +1,247 @@
+// pkg/tokens/tokens.go
+package tokens
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for image dimensions
+	_ "image/jpeg" // register JPEG for image dimensions
+	_ "image/png"  // register PNG for image dimensions
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// Limits the API applies to images before counting their tokens. Larger
+// images are scaled down to fit.
+const (
+	MaxImageEdge   = 1568
+	MaxImagePixels = 1_150_000
+)
+
+// Factors convert the Features of a request into tokens. They are the
+// coefficients of a linear model that Fit calibrates from recorded usage.
+type Factors struct {
+	Base      float64 `json:"base"`
+	PerChar   float64 `json:"per_char"`
+	PerPixel  float64 `json:"per_pixel"`
+	PerPage   float64 `json:"per_page"`
+	PerSchema float64 `json:"per_schema_char"`
+	// PerTools is added once when the request defines any tools, for the
+	// tool-use instructions the API adds to the system prompt.
+	PerTools   float64 `json:"per_tools"`
+	PerMessage float64 `json:"per_message"`
+}
+
+// DefaultFactors follow the published rules of thumb: about four characters
+// per token, width*height/750 tokens per image and a few thousand tokens per
+// PDF page.
+var DefaultFactors = Factors{
+	Base:       4,
+	PerChar:    0.25,
+	PerPixel:   1.0 / 750,
+	PerPage:    2000,
+	PerSchema:  0.3,
+	PerTools:   350,
+	PerMessage: 4,
+}
+
+// LoadFactors reads factors written as JSON, e.g. by the token-calibrate
+// command.
+func LoadFactors(path string) (Factors, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Factors{}, err
+	}
+	var f Factors
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Factors{}, fmt.Errorf("failed to decode factors %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Features are the measured sizes of a request that its token count depends
+// on.
+type Features struct {
+	Chars       int
+	Pixels      int
+	Pages       int
+	SchemaChars int
+	Tools       int
+	Messages    int
+	// UnknownPages counts the PDFs whose page count could not be read. Each
+	// counts as one page in Pages, so Pages is a lower bound when it is set.
+	UnknownPages int
+}
+
+// vector returns the features in the order of Factors.vector.
+func (f Features) vector() []float64 {
+	hasTools := 0.0
+	if f.Tools > 0 {
+		hasTools = 1
+	}
+	return []float64{1, float64(f.Chars), float64(f.Pixels), float64(f.Pages), float64(f.SchemaChars), hasTools, float64(f.Messages)}
+}
+
+func (f Factors) vector() []float64 {
+	return []float64{f.Base, f.PerChar, f.PerPixel, f.PerPage, f.PerSchema, f.PerTools, f.PerMessage}
+}
+
+func factorsOf(v []float64) Factors {
+	return Factors{Base: v[0], PerChar: v[1], PerPixel: v[2], PerPage: v[3], PerSchema: v[4], PerTools: v[5], PerMessage: v[6]}
+}
+
+// Apply returns the tokens f assigns to features.
+func (f Factors) Apply(features Features) float64 {
+	var n float64
+	x := features.vector()
+	for i, factor := range f.vector() {
+		n += factor * x[i]
+	}
+	return n
+}
+
+// Estimator counts the input tokens of a request locally, without calling
+// the API. The count is approximate; calibrate the factors against recorded
+// usage to tighten it.
+type Estimator struct {
+	Factors Factors
+}
+
+// NewEstimator creates an Estimator with factors.
+func NewEstimator(factors Factors) *Estimator {
+	return &Estimator{Factors: factors}
+}
+
+// DefaultEstimator uses DefaultFactors.
+var DefaultEstimator = NewEstimator(DefaultFactors)
+
+// Estimate returns the estimated input tokens of req.
+func (e *Estimator) Estimate(req *models.MessageRequest) int {
+	return int(math.Ceil(e.Factors.Apply(Measure(req))))
+}
+
+// Measure returns the features of req.
+//
+// Images are measured by their dimensions after the API's scaling; images
+// given by URL or in formats without a registered decoder count as the
+// largest image. PDFs are measured by page count, read from the page tree.
+// PDFs given by URL, and PDFs whose page tree is hidden in a compressed object
+// stream as PDF 1.5 and later allow, count as one page and are counted in
+// UnknownPages, so multi-page documents among them are undercounted. Text
+// documents, tool inputs and tool results count as text.
+func Measure(req *models.MessageRequest) Features {
+	f := Features{Chars: len(req.System), Messages: len(req.Messages), Tools: len(req.Tools)}
+	for _, tool := range req.Tools {
+		f.SchemaChars += len(tool.Name) + len(tool.Description) + len(tool.InputSchema)
+	}
+	for _, message := range req.Messages {
+		if len(message.Blocks) == 0 {
+			f.Chars += len(message.Content)
+		}
+		for _, block := range message.Blocks {
+			f.Chars += len(block.Text) + len(block.Input) + len(block.Content)
+			switch block.Type {
+			case models.ImageBlock:
+				f.Pixels += imagePixels(block.Source)
+			case models.DocumentBlock:
+				measureDocument(&f, block.Source)
+			}
+		}
+	}
+	return f
+}
+
+func measureDocument(f *Features, source *models.Source) {
+	switch {
+	case source == nil:
+	case source.Type == models.TextSource:
+		f.Chars += len(source.Data)
+	case source.Type == models.Base64Source:
+		n, ok := pdfPages(source.Data)
+		if !ok {
+			f.UnknownPages++
+			n = 1
+		}
+		f.Pages += n
+	default:
+		f.UnknownPages++
+		f.Pages++
+	}
+}
+
+// imagePixels returns the pixel count of an image after scaling.
+func imagePixels(source *models.Source) int {
+	if source == nil || source.Type != models.Base64Source {
+		return MaxImagePixels
+	}
+	data, err := base64.StdEncoding.DecodeString(source.Data)
+	if err != nil {
+		return MaxImagePixels
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return MaxImagePixels
+	}
+	return ScaledPixels(config.Width, config.Height)
+}
+
+// ScaledPixels returns the pixel count of a width x height image after the
+// API scales it to MaxImageEdge and MaxImagePixels.
+func ScaledPixels(width, height int) int {
+	w, h := float64(width), float64(height)
+	if edge := math.Max(w, h); edge > MaxImageEdge {
+		w, h = w*MaxImageEdge/edge, h*MaxImageEdge/edge
+	}
+	if pixels := w * h; pixels > MaxImagePixels {
+		scale := math.Sqrt(MaxImagePixels / pixels)
+		w, h = w*scale, h*scale
+	}
+	return int(w * h)
+}
+
+var (
+	// pdfDict matches a dictionary without nested dictionaries.
+	pdfDict = regexp.MustCompile(`<<[^<>]*>>`)
+	// pdfPageTree matches a /Type /Pages page tree node.
+	pdfPageTree = regexp.MustCompile(`/Type\s*/Pages\b`)
+	// pdfCount matches the page count of a page tree node.
+	pdfCount = regexp.MustCompile(`/Count\s+(\d+)`)
+	// pdfPage matches a page object, but not the /Type /Pages tree nodes.
+	pdfPage = regexp.MustCompile(`/Type\s*/Page([^s]|$)`)
+)
+
+// pdfPages counts the pages of a base64 PDF. It prefers the /Count of the
+// page tree root, which is the largest count of any page tree node, and
+// otherwise counts page objects. It reports false if neither is visible, as
+// when the page tree is stored in a compressed object stream.
+func pdfPages(data string) (int, bool) {
+	pdf, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return 0, false
+	}
+	count := 0
+	for _, dict := range pdfDict.FindAll(pdf, -1) {
+		if !pdfPageTree.Match(dict) {
+			continue
+		}
+		if m := pdfCount.FindSubmatch(dict); m != nil {
+			if n, err := strconv.Atoi(string(m[1])); err == nil && n > count {
+				count = n
+			}
+		}
+	}
+	if count > 0 {
+		return count, true
+	}
+	if n := len(pdfPage.FindAllIndex(pdf, -1)); n > 0 {
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,332 @@
+// pkg/tracing/tracing.go
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+const instrumentationName = "github.com/Aanthord/go-anthropic/pkg/tracing"
+
+// GenAI semantic-convention attribute keys.
+const (
+	SystemKey                   = attribute.Key("gen_ai.system")
+	OperationNameKey            = attribute.Key("gen_ai.operation.name")
+	RequestModelKey             = attribute.Key("gen_ai.request.model")
+	RequestMaxTokensKey         = attribute.Key("gen_ai.request.max_tokens")
+	RequestTemperatureKey       = attribute.Key("gen_ai.request.temperature")
+	RequestTopPKey              = attribute.Key("gen_ai.request.top_p")
+	ResponseIDKey               = attribute.Key("gen_ai.response.id")
+	ResponseModelKey            = attribute.Key("gen_ai.response.model")
+	ResponseFinishReasonsKey    = attribute.Key("gen_ai.response.finish_reasons")
+	UsageInputTokensKey         = attribute.Key("gen_ai.usage.input_tokens")
+	UsageOutputTokensKey        = attribute.Key("gen_ai.usage.output_tokens")
+	UsageCacheCreationTokensKey = attribute.Key("gen_ai.usage.cache_creation_input_tokens")
+	UsageCacheReadTokensKey     = attribute.Key("gen_ai.usage.cache_read_input_tokens")
+	RequestIDKey                = attribute.Key("anthropic.request_id")
+	ToolNameKey                 = attribute.Key("gen_ai.tool.name")
+	ToolCallIDKey               = attribute.Key("gen_ai.tool.call.id")
+)
+
+// LabelKeyPrefix prefixes the span attributes set from call labels, e.g.
+// anthropic.label.prompt for the prompt label.
+const LabelKeyPrefix = "anthropic.label."
+
+// FirstTokenEvent is the span event recorded when a stream yields its first chunk.
+const FirstTokenEvent = "gen_ai.first_token"
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+}
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider. The global provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithPropagators sets the propagators used to inject trace context into
+// outgoing requests. The global propagator is used by default.
+func WithPropagators(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = p
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		propagators:    otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return c.tracerProvider.Tracer(instrumentationName)
+}
+
+// WithTracing returns a ClientOption that creates a span for every client call
+// and a child span for every HTTP attempt, including retries. It must be
+// applied after any WithHTTPClient option.
+func WithTracing(opts ...Option) api.ClientOption {
+	return func(c *api.Client) {
+		c.Middleware = append(c.Middleware, Middleware(opts...))
+		httpClient := *c.HTTPClient
+		httpClient.Transport = Transport(httpClient.Transport, opts...)
+		c.HTTPClient = &httpClient
+	}
+}
+
+type attemptKey struct{}
+
+// Middleware returns middleware that creates a span per client call and
+// records GenAI attributes of the request and response.
+func Middleware(opts ...Option) api.Middleware {
+	cfg := newConfig(opts)
+	tracer := cfg.tracer()
+
+	return func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+		operation := operationName(call.Operation)
+		attrs := []attribute.KeyValue{
+			SystemKey.String("anthropic"),
+			OperationNameKey.String(operation),
+		}
+		name := operation
+		if req := call.MessageRequest(); req != nil {
+			attrs = append(attrs, requestAttributes(req)...)
+			if req.Model != "" {
+				name += " " + req.Model
+			}
+		}
+		for key, value := range call.Labels {
+			attrs = append(attrs, attribute.String(LabelKeyPrefix+key, value))
+		}
+
+		ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		ctx = context.WithValue(ctx, attemptKey{}, new(int32))
+
+		result, err := next(ctx, call)
+		if result != nil && result.HTTPResponse != nil {
+			if id := result.HTTPResponse.Header.Get("request-id"); id != "" {
+				span.SetAttributes(RequestIDKey.String(id))
+			}
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
+			return result, err
+		}
+
+		if call.Stream && result.Stream != nil {
+			result.Stream, result.StreamErrors = traceStream(span, result.Stream, result.StreamErrors)
+			return result, nil
+		}
+		if resp := result.MessageResponse(); resp != nil {
+			span.SetAttributes(responseAttributes(resp)...)
+		}
+		span.End()
+		return result, nil
+	}
+}
+
+// traceStream forwards stream chunks and errors, records the time to first
+// token and ends the span when the stream is drained. The first stream error
+// marks the span as failed.
+func traceStream(span trace.Span, stream <-chan models.MessageResponse, errs <-chan error) (<-chan models.MessageResponse, <-chan error) {
+	output := make(chan models.MessageResponse)
+	errCh := make(chan error)
+
+	go func() {
+		defer close(output)
+		defer close(errCh)
+		defer span.End()
+
+		var last models.MessageResponse
+		first := true
+		failed := false
+		for stream != nil || errs != nil {
+			select {
+			case chunk, ok := <-stream:
+				if !ok {
+					stream = nil
+					continue
+				}
+				if first {
+					span.AddEvent(FirstTokenEvent)
+					first = false
+				}
+				mergeChunk(&last, chunk)
+				output <- chunk
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				span.RecordError(err)
+				if !failed {
+					span.SetStatus(codes.Error, err.Error())
+					failed = true
+				}
+				errCh <- err
+			}
+		}
+		span.SetAttributes(responseAttributes(&last)...)
+	}()
+
+	return output, errCh
+}
+
+// mergeChunk folds the identifying fields, usage and finish reason of a stream
+// chunk into the running summary.
+func mergeChunk(summary *models.MessageResponse, chunk models.MessageResponse) {
+	if chunk.ID != "" {
+		summary.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		summary.Model = chunk.Model
+	}
+	if chunk.Usage != (models.MessageUsage{}) {
+		summary.Usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != "" {
+			summary.Choices = []models.MessageChoice{{FinishReason: choice.FinishReason}}
+		}
+	}
+}
+
+func requestAttributes(req *models.MessageRequest) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		RequestModelKey.String(req.Model),
+		RequestMaxTokensKey.Int(req.MaxTokens),
+	}
+	if req.Temperature != 0 {
+		attrs = append(attrs, RequestTemperatureKey.Float64(float64(req.Temperature)))
+	}
+	if req.TopP != 0 {
+		attrs = append(attrs, RequestTopPKey.Float64(float64(req.TopP)))
+	}
+	return attrs
+}
+
+func responseAttributes(resp *models.MessageResponse) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		UsageInputTokensKey.Int(resp.Usage.PromptTokens),
+		UsageOutputTokensKey.Int(resp.Usage.CompletionTokens),
+		UsageCacheCreationTokensKey.Int(resp.Usage.CacheCreationInputTokens),
+		UsageCacheReadTokensKey.Int(resp.Usage.CacheReadInputTokens),
+	}
+	if resp.ID != "" {
+		attrs = append(attrs, ResponseIDKey.String(resp.ID))
+	}
+	if resp.Model != "" {
+		attrs = append(attrs, ResponseModelKey.String(resp.Model))
+	}
+	var reasons []string
+	for _, choice := range resp.Choices {
+		if choice.FinishReason != "" {
+			reasons = append(reasons, choice.FinishReason)
+		}
+	}
+	if len(reasons) > 0 {
+		attrs = append(attrs, ResponseFinishReasonsKey.StringSlice(reasons))
+	}
+	return attrs
+}
+
+// operationName maps a client operation to its GenAI operation name.
+func operationName(operation string) string {
+	switch operation {
+	case "CreateMessage", "StreamMessages":
+		return "chat"
+	case "CountTokens":
+		return "count_tokens"
+	case "ListModels":
+		return "list_models"
+	}
+	return operation
+}
+
+type transport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+	cfg    *config
+}
+
+// Transport wraps base so that every HTTP attempt, including retries, gets its
+// own span. A nil base uses http.DefaultTransport.
+func Transport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	cfg := newConfig(opts)
+	return &transport{base: base, tracer: cfg.tracer(), cfg: cfg}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Hostname()),
+	}
+	if counter, ok := ctx.Value(attemptKey{}).(*int32); ok {
+		if n := atomic.AddInt32(counter, 1) - 1; n > 0 {
+			attrs = append(attrs, attribute.Int("http.request.resend_count", int(n)))
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	t.cfg.propagators.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if id := resp.Header.Get("request-id"); id != "" {
+		span.SetAttributes(RequestIDKey.String(id))
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// StartToolSpan starts a span for executing a tool between turns of a tool-use
+// loop. Passing the returned context to the next CreateMessage call keeps the
+// whole loop in one trace.
+func StartToolSpan(ctx context.Context, toolName, toolUseID string, opts ...Option) (context.Context, trace.Span) {
+	tracer := newConfig(opts).tracer()
+	return tracer.Start(ctx, "execute_tool "+toolName, trace.WithAttributes(
+		SystemKey.String("anthropic"),
+		OperationNameKey.String("execute_tool"),
+		ToolNameKey.String(toolName),
+		ToolCallIDKey.String(toolUseID),
+	))
+}
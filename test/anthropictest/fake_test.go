@@ -0,0 +1,86 @@
+// test/anthropictest/fake_test.go
+package anthropictest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// summarize stands in for application code that depends only on the interface.
+func summarize(ctx context.Context, client api.MessageClient, text string) (string, error) {
+	resp, err := client.CreateMessage(ctx, newRequest("Summarize: "+text))
+	if err != nil {
+		return "", err
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func TestFake(t *testing.T) {
+	fake := anthropictest.NewFake()
+	fake.EnqueueText("Short summary")
+	fake.EnqueueError(anthropictest.OverloadedError())
+
+	summary, err := summarize(context.Background(), fake, "a long text")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary != "Short summary" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+	if _, err := summarize(context.Background(), fake, "again"); err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Errorf("expected overloaded error, got %v", err)
+	}
+
+	fake.Respond = func(req *models.MessageRequest) anthropictest.Response {
+		return anthropictest.Response{Message: anthropictest.TextMessage(strings.ToUpper(req.Messages[0].Content))}
+	}
+	fake.ChunkSize = 3
+	stream, errs := fake.StreamMessages(context.Background(), newRequest("shout"))
+	var content string
+	var finish string
+	for chunk := range stream {
+		for _, choice := range chunk.Choices {
+			content += choice.Message.Content
+			if choice.FinishReason != "" {
+				finish = choice.FinishReason
+			}
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content != "SHOUT" || finish != "end_turn" {
+		t.Errorf("unexpected stream content %q with finish reason %q", content, finish)
+	}
+
+	count, err := fake.CountTokens(context.Background(), newRequest("12345678"))
+	if err != nil || count.InputTokens != 2 {
+		t.Errorf("unexpected token count %+v, %v", count, err)
+	}
+
+	fake.AssertQueueEmpty(t)
+	fake.AssertCallCount(t, "CreateMessage", 2)
+	fake.AssertCallCount(t, "StreamMessages", 1)
+	if last := fake.LastRequest(); last == nil || last.Messages[0].Content != "12345678" {
+		t.Errorf("unexpected last request %+v", last)
+	}
+}
+
+func TestClientCountTokens(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+
+	count, err := newClient(server).CountTokens(context.Background(), newRequest("12345678"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count.InputTokens != 2 {
+		t.Errorf("expected 2 tokens, got %d", count.InputTokens)
+	}
+	server.AssertRequestCount(t, "/v1/messages/count_tokens", 1)
+}
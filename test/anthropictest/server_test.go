@@ -0,0 +1,197 @@
+// test/anthropictest/server_test.go
+package anthropictest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func newClient(server *anthropictest.Server) *api.Client {
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+func newRequest(content string) *models.MessageRequest {
+	return &models.MessageRequest{
+		Messages:  []models.Message{{Role: models.UserRole, Content: content}},
+		MaxTokens: 64,
+	}
+}
+
+func TestScriptedResponses(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	client := newClient(server)
+
+	server.EnqueueText("Scripted reply")
+	server.EnqueueToolUse("toolu_1", "get_weather", map[string]string{"city": "Paris"})
+	server.EnqueueError(anthropictest.RateLimitError())
+
+	resp, err := client.CreateMessage(context.Background(), newRequest("Hi"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Scripted reply" {
+		t.Errorf("unexpected content %q", resp.Choices[0].Message.Content)
+	}
+	if resp.ID == "" || resp.Usage.PromptTokens == 0 {
+		t.Errorf("expected ID and usage to be filled in, got %+v", resp)
+	}
+
+	resp, err = client.CreateMessage(context.Background(), newRequest("Weather?"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	blocks := resp.Choices[0].Message.Blocks
+	if resp.Choices[0].FinishReason != "tool_use" || len(blocks) != 1 || blocks[0].Name != "get_weather" {
+		t.Fatalf("unexpected tool use response %+v", resp.Choices[0])
+	}
+	var input map[string]string
+	if err := json.Unmarshal(blocks[0].Input, &input); err != nil || input["city"] != "Paris" {
+		t.Errorf("unexpected tool input %s", blocks[0].Input)
+	}
+
+	if _, err := client.CreateMessage(context.Background(), newRequest("Again")); err == nil || !strings.Contains(err.Error(), "rate_limit_error") {
+		t.Errorf("expected rate limit error, got %v", err)
+	}
+
+	resp, err = client.CreateMessage(context.Background(), newRequest("Echo me"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "Echo me" {
+		t.Errorf("expected echo, got %q", resp.Choices[0].Message.Content)
+	}
+
+	server.AssertQueueEmpty(t)
+	server.AssertRequestCount(t, "/v1/messages", 4)
+	if last := server.LastMessageRequest(); last == nil || last.Messages[0].Content != "Echo me" {
+		t.Errorf("unexpected last request %+v", last)
+	}
+	if key := server.Requests()[0].Header.Get("X-API-Key"); key != "api-key" {
+		t.Errorf("expected API key header, got %q", key)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	server.ChunkSize = 4
+	client := newClient(server)
+
+	server.EnqueueText("Hello streaming world")
+	stream, errs := client.StreamMessages(context.Background(), newRequest("Hi"))
+	var content string
+	chunks := 0
+	for chunk := range stream {
+		chunks++
+		if len(chunk.Choices) > 0 {
+			content += chunk.Choices[0].Message.Content
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content != "Hello streaming world" {
+		t.Errorf("unexpected content %q", content)
+	}
+	if chunks < 3 {
+		t.Errorf("expected text to be split into chunks, got %d", chunks)
+	}
+
+	server.Enqueue(anthropictest.Response{
+		Message:          anthropictest.TextMessage("Interrupted reply"),
+		StreamError:      anthropictest.OverloadedError(),
+		StreamErrorAfter: 1,
+	})
+	stream, errs = client.StreamMessages(context.Background(), newRequest("Hi"))
+	var streamErr error
+	for stream != nil || errs != nil {
+		select {
+		case _, ok := <-stream:
+			if !ok {
+				stream = nil
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+			} else if streamErr == nil {
+				streamErr = err
+			}
+		}
+	}
+	if streamErr == nil || !strings.Contains(streamErr.Error(), "overloaded_error") {
+		t.Errorf("expected mid-stream overloaded error, got %v", streamErr)
+	}
+}
+
+func TestCountTokensModelsAndBatches(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+
+	body := strings.NewReader(`{"messages":[{"role":"user","content":"12345678"}]}`)
+	resp, err := http.Post(server.URL+"/v1/messages/count_tokens", "application/json", body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count models.CountTokensResponse
+	if err := json.NewDecoder(resp.Body).Decode(&count); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	resp.Body.Close()
+	if count.InputTokens != 2 {
+		t.Errorf("expected 2 tokens, got %d", count.InputTokens)
+	}
+
+	list, err := newClient(server).ListModels(context.Background(), "", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Models) != 1 || list.Models[0].ID != "claude-test" {
+		t.Errorf("unexpected models %+v", list.Models)
+	}
+
+	server.EnqueueError(anthropictest.InvalidRequestError("bad"))
+	create, _ := json.Marshal(models.CreateBatchRequest{Requests: []models.BatchRequest{
+		{CustomID: "a", Params: *newRequest("first")},
+		{CustomID: "b", Params: *newRequest("second")},
+	}})
+	resp, err = http.Post(server.URL+"/v1/messages/batches", "application/json", strings.NewReader(string(create)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var batch models.Batch
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	resp.Body.Close()
+	if batch.ProcessingStatus != models.BatchEnded || batch.RequestCounts.Errored != 1 || batch.RequestCounts.Succeeded != 1 {
+		t.Errorf("unexpected batch %+v", batch)
+	}
+
+	resp, err = http.Get(batch.ResultsURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	var results []models.BatchResult
+	for decoder.More() {
+		var result models.BatchResult
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		results = append(results, result)
+	}
+	if len(results) != 2 || results[0].Result.Type != "errored" || results[1].Result.Message.Choices[0].Message.Content != "second" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
@@ -0,0 +1,269 @@
+// test/api/bulk_test.go
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// echoClient answers every request with its own text, later requests first.
+// fail decides whether an attempt of a request fails with its error.
+type echoClient struct {
+	api.MessageClient
+	mu       sync.Mutex
+	attempts map[string]int
+	inFlight int32
+	peak     int32
+	fail     func(text string, attempt int) error
+}
+
+func (c *echoClient) CreateMessage(ctx context.Context, req *models.MessageRequest, opts ...api.RequestOption) (*models.MessageResponse, error) {
+	n := atomic.AddInt32(&c.inFlight, 1)
+	defer atomic.AddInt32(&c.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&c.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&c.peak, peak, n) {
+			break
+		}
+	}
+
+	text := req.Messages[0].Content
+	c.mu.Lock()
+	if c.attempts == nil {
+		c.attempts = make(map[string]int)
+	}
+	c.attempts[text]++
+	attempt := c.attempts[text]
+	c.mu.Unlock()
+
+	var index int
+	fmt.Sscanf(text, "req-%d", &index)
+	time.Sleep(time.Duration(20-index%20) * time.Millisecond)
+	if c.fail != nil {
+		if err := c.fail(text, attempt); err != nil {
+			return nil, err
+		}
+	}
+	return &models.MessageResponse{Choices: []models.MessageChoice{{Message: models.Message{Role: models.AssistantRole, Content: text}}}}, nil
+}
+
+func bulkRequests(n int) []*models.MessageRequest {
+	requests := make([]*models.MessageRequest, n)
+	for i := range requests {
+		requests[i] = &models.MessageRequest{Messages: []models.Message{{Role: models.UserRole, Content: fmt.Sprintf("req-%d", i)}}}
+	}
+	return requests
+}
+
+func rateLimitError(t *testing.T) error {
+	fake := anthropictest.NewFake()
+	fake.EnqueueError(anthropictest.RateLimitError())
+	_, err := fake.CreateMessage(context.Background(), &models.MessageRequest{})
+	if !api.IsRetryable(err) {
+		t.Fatalf("expected a retryable rate limit error, got %v", err)
+	}
+	return err
+}
+
+func TestBulkExecutorRun(t *testing.T) {
+	rateErr := rateLimitError(t)
+	client := &echoClient{fail: func(text string, attempt int) error {
+		if (text == "req-3" || text == "req-7") && attempt == 1 {
+			return rateErr
+		}
+		if text == "req-9" {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+
+	var last api.BulkProgress
+	executor := api.NewBulkExecutor(client, 4)
+	executor.MinRetryDelay = time.Millisecond
+	executor.OnProgress = func(p api.BulkProgress) { last = p }
+	results, err := executor.Run(context.Background(), bulkRequests(20))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, result := range results {
+		want := fmt.Sprintf("req-%d", i)
+		switch {
+		case result.Index != i:
+			t.Errorf("expected result %d in position %d", result.Index, i)
+		case i == 9:
+			if result.Err == nil || result.Attempts != 3 {
+				t.Errorf("expected req-9 to fail after 3 attempts, got %+v", result)
+			}
+		case result.Err != nil || result.Response.Text() != want:
+			t.Errorf("unexpected result %d: %+v", i, result)
+		case (i == 3 || i == 7) != (result.Attempts == 2):
+			t.Errorf("unexpected attempts for %d: %d", i, result.Attempts)
+		}
+	}
+	if peak := atomic.LoadInt32(&client.peak); peak > 4 {
+		t.Errorf("expected at most 4 calls in flight, got %d", peak)
+	}
+	if last != (api.BulkProgress{Total: 20, Completed: 20, Failed: 1}) {
+		t.Errorf("unexpected final progress %+v", last)
+	}
+}
+
+func TestBulkExecutorDefaultDelay(t *testing.T) {
+	client := &echoClient{fail: func(text string, attempt int) error {
+		if attempt == 1 {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+	executor := api.NewBulkExecutor(client, 1)
+	executor.MinRetryDelay = 0
+	executor.MaxRetryDelay = 50 * time.Millisecond
+
+	start := time.Now()
+	results, err := executor.Run(context.Background(), bulkRequests(1))
+	if err != nil || results[0].Err != nil || results[0].Attempts != 2 {
+		t.Fatalf("unexpected result %+v: %v", results, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected a zero MinRetryDelay to fall back to a delay, retried after %s", elapsed)
+	}
+}
+
+func TestBulkExecutorStream(t *testing.T) {
+	requests := make(chan *models.MessageRequest)
+	go func() {
+		defer close(requests)
+		for _, req := range bulkRequests(10) {
+			requests <- req
+		}
+	}()
+
+	results, err := api.NewBulkExecutor(&echoClient{}, 3).Stream(context.Background(), requests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next := 0
+	for result := range results {
+		if result.Index != next || result.Response.Text() != fmt.Sprintf("req-%d", next) {
+			t.Errorf("unexpected result in position %d: %+v", next, result)
+		}
+		next++
+	}
+	if next != 10 {
+		t.Errorf("expected 10 results, got %d", next)
+	}
+}
+
+func TestBulkExecutorResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	checkpoint, err := api.OpenFileCheckpoint(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first run fails the second half of the requests.
+	crashed := &echoClient{fail: func(text string, attempt int) error {
+		var index int
+		fmt.Sscanf(text, "req-%d", &index)
+		if index >= 5 {
+			return context.Canceled
+		}
+		return nil
+	}}
+	executor := api.NewBulkExecutor(crashed, 2)
+	executor.Checkpoint = checkpoint
+	if _, err := executor.Run(context.Background(), bulkRequests(10)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkpoint.Close()
+
+	// Simulate a crash in the middle of writing an entry.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.WriteString(`{"index":5,"respo`)
+	f.Close()
+
+	checkpoint, err = api.OpenFileCheckpoint(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer checkpoint.Close()
+	resumed := &echoClient{}
+	executor = api.NewBulkExecutor(resumed, 2)
+	executor.Checkpoint = checkpoint
+	results, err := executor.Run(context.Background(), bulkRequests(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, result := range results {
+		if result.Err != nil || result.Response.Text() != fmt.Sprintf("req-%d", i) || result.Resumed != (i < 5) {
+			t.Errorf("unexpected result %d: %+v", i, result)
+		}
+	}
+	if len(resumed.attempts) != 5 {
+		t.Errorf("expected only the unfinished requests to be sent, got %v", resumed.attempts)
+	}
+
+	saved, err := checkpoint.Load()
+	if err != nil || len(saved) != 10 {
+		t.Errorf("expected all 10 responses in the checkpoint, got %d (%v)", len(saved), err)
+	}
+}
+
+type countingLimiter struct{ waits int32 }
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	atomic.AddInt32(&l.waits, 1)
+	return nil
+}
+
+func TestRateLimiter(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	server.EnqueueText("one")
+	server.EnqueueText("two")
+	limiter := &countingLimiter{}
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()), api.WithRateLimiter(limiter))
+	client.SetBaseURL(server.URL)
+
+	for i := 0; i < 2; i++ {
+		req := &models.MessageRequest{Model: "claude-3-5-haiku-20241022", MaxTokens: 16, Messages: []models.Message{{Role: models.UserRole, Content: "Hi"}}}
+		if _, err := client.CreateMessage(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if limiter.waits != 2 {
+		t.Errorf("expected 2 waits, got %d", limiter.waits)
+	}
+
+	bucket := api.NewRateLimiter(1200, 1)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := bucket.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected 3 requests at 20/s to take about 100ms, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := api.NewRateLimiter(1, 1).Wait(ctx); err != nil {
+		t.Errorf("expected the first request of a full bucket to pass, got %v", err)
+	}
+}
@@ -2,32 +2,32 @@
 package api_test
 
 import (
-    "errors"
+    "log/slog"
     "net/http"  
     "net/http/httptest"
     "testing"
     "time"
 
-    "github.com/Aanthord/anthropic-go/internal/logging"
-    "github.com/Aanthord/anthropic-go/internal/retry"  
-    "github.com/Aanthord/anthropic-go/pkg/api"
+    "github.com/Aanthord/go-anthropic/pkg/api"
 )
 
+// noRetry is a retrier that makes a single attempt.
+type noRetry struct{}
+
+func (noRetry) Do(fn func() (*http.Response, error)) (*http.Response, error) {
+    return fn()
+}
+
 func TestClientOptions(t *testing.T) {
     testCases := []struct {
         name        string
         apiKey      string
         opts        []api.ClientOption
-        expectedErr error  
     }{
         {
             name:   "valid API key",
             apiKey: "valid-api-key", 
         },
-        {
-            name:        "missing API key",  
-            expectedErr: errors.New("missing API key"),
-        },
         {
             name:   "with HTTP client",
             apiKey: "valid-api-key", 
@@ -36,29 +36,18 @@ func TestClientOptions(t *testing.T) {
         {
             name:   "with logger", 
             apiKey: "valid-api-key",
-            opts:   []api.ClientOption{api.WithLogger(logging.NewNopLogger())},  
+            opts:   []api.ClientOption{api.WithSlogLogger(slog.Default(), api.DefaultRedactionPolicy)},  
         },
         {
             name:   "with retrier",
             apiKey: "valid-api-key",
-            opts:   []api.ClientOption{api.WithRetrier(retry.NewNoOpRetrier())},
+            opts:   []api.ClientOption{api.WithRetrier(noRetry{})},
         },  
     }
 
     for _, tc := range testCases {
         t.Run(tc.name, func(t *testing.T) {
-            client, err := api.NewClient(tc.apiKey, tc.opts...)
-            if tc.expectedErr != nil {
-                if err == nil || err.Error() != tc.expectedErr.Error() {
-                    t.Errorf("expected error %v, got %v", tc.expectedErr, err)  
-                }
-                return
-            }
-
-            if err != nil {
-                t.Errorf("unexpected error: %v", err)
-            }
-
+            client := api.NewClient(tc.apiKey, tc.opts...)
             if client == nil {
                 t.Error("expected non-nil client")  
             }  
@@ -86,12 +75,7 @@ func TestClientDo(t *testing.T) {
     client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
     client.SetBaseURL(server.URL)
 
-    req, err := http.NewRequest("GET", "/", nil)
-    if err != nil {
-        t.Fatalf("failed to create request: %v", err) 
-    }
-
-    resp, err := client.Do(req)
+    resp, err := client.Get("/")
     if err != nil {
         t.Fatalf("failed to make request: %v", err)
     }
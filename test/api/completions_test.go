@@ -2,16 +2,14 @@
 package api_test
 
 import (
-    "bytes"
     "context"
     "encoding/json"
-    "io/ioutil"
     "net/http"
     "net/http/httptest"
     "testing"
 
-    "github.com/Aanthord/anthropic-go/pkg/api"
-    "github.com/Aanthord/anthropic-go/pkg/models"
+    "github.com/Aanthord/go-anthropic/pkg/api"
+    "github.com/Aanthord/go-anthropic/pkg/models"
 )
 
 func TestCreateCompletion(t *testing.T) {
@@ -161,24 +159,23 @@ func TestStreamCompletions(t *testing.T) {
         w.Header().Set("Content-Type", "text/event-stream")
         w.WriteHeader(http.StatusOK)
 
-        enc := json.NewEncoder(w)
         dataPrefix := []byte("data: ")
 
         data, _ := json.Marshal(expectedResp1)
         w.Write(dataPrefix)
-        enc.Encode(string(data))
+        w.Write(data)
         w.Write([]byte("\n\n"))
         w.(http.Flusher).Flush()
 
         data, _ = json.Marshal(expectedResp2)
         w.Write(dataPrefix)
-        enc.Encode(string(data))
+        w.Write(data)
         w.Write([]byte("\n\n"))
         w.(http.Flusher).Flush()
 
         data, _ = json.Marshal(expectedResp3)
         w.Write(dataPrefix)
-        enc.Encode(string(data))
+        w.Write(data)
         w.Write([]byte("\n\n"))
         w.(http.Flusher).Flush()
     }))
@@ -223,3 +220,62 @@ func TestStreamCompletions(t *testing.T) {
         t.Errorf("expected response %q, got %q", expectedFullResponse, fullResponse)
     }
 }
+
+func TestCompletionsUseMiddleware(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("X-Custom") != "value" {
+            t.Errorf("expected request options to apply, got headers %v", r.Header)
+        }
+        var req models.CompletionRequest
+        _ = json.NewDecoder(r.Body).Decode(&req)
+        if req.Model != "claude-default" {
+            t.Errorf("expected the default model, got %q", req.Model)
+        }
+        if req.Stream {
+            w.Write([]byte("data: {\"choices\":[{\"text\":\"streamed\"}]}\n\n"))
+            return
+        }
+        json.NewEncoder(w).Encode(models.CompletionResponse{ID: "cmpl-1"})
+    }))
+    defer server.Close()
+
+    var operations []string
+    client := api.NewClient("dummy-api-key", api.WithHTTPClient(server.Client()), api.WithDefaultModel("claude-default"), api.WithMiddleware(
+        func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+            operations = append(operations, call.Operation)
+            return next(ctx, call)
+        },
+    ))
+    client.SetBaseURL(server.URL)
+
+    resp, err := client.CreateCompletion(context.Background(), &models.CompletionRequest{Prompt: "test prompt"}, api.WithHeader("X-Custom", "value"))
+    if err != nil || resp.ID != "cmpl-1" {
+        t.Fatalf("unexpected completion %+v: %v", resp, err)
+    }
+
+    stream, errs := client.StreamCompletions(context.Background(), &models.CompletionRequest{Prompt: "test prompt"}, api.WithHeader("X-Custom", "value"))
+    var text string
+    for stream != nil || errs != nil {
+        select {
+        case chunk, ok := <-stream:
+            if !ok {
+                stream = nil
+                continue
+            }
+            text += chunk.Choices[0].Text
+        case err, ok := <-errs:
+            if !ok {
+                errs = nil
+                continue
+            }
+            t.Errorf("unexpected error: %v", err)
+        }
+    }
+    if text != "streamed" {
+        t.Errorf("expected streamed text, got %q", text)
+    }
+
+    if len(operations) != 2 || operations[0] != "CreateCompletion" || operations[1] != "StreamCompletions" {
+        t.Errorf("expected both calls to pass through middleware, got %v", operations)
+    }
+}
@@ -0,0 +1,130 @@
+// test/api/continuation_test.go
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// truncated returns a scripted response cut off with stop reason reason.
+func truncated(text, reason string, outputTokens int) anthropictest.Response {
+	message := anthropictest.TextMessage(text)
+	message.Choices[0].FinishReason = reason
+	message.Usage.CompletionTokens = outputTokens
+	return anthropictest.Response{Message: message}
+}
+
+func TestContinuation(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		name := "create"
+		if stream {
+			name = "stream"
+		}
+		t.Run(name, func(t *testing.T) {
+			server := anthropictest.NewServer()
+			defer server.Close()
+			server.ChunkSize = 3
+			client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
+			client.SetBaseURL(server.URL)
+
+			server.Enqueue(
+				truncated("Once upon ", "max_tokens", 10),
+				truncated("a time", "pause_turn", 5),
+				truncated(" the end.", "end_turn", 3),
+			)
+			req := &models.MessageRequest{
+				Messages:  []models.Message{{Role: models.UserRole, Content: "Tell a story"}},
+				MaxTokens: 10,
+			}
+
+			var content, finish string
+			var usage models.MessageUsage
+			if stream {
+				chunks, errs := client.StreamMessages(context.Background(), req, api.WithContinuation(100))
+				for chunks != nil || errs != nil {
+					select {
+					case chunk, ok := <-chunks:
+						if !ok {
+							chunks = nil
+							continue
+						}
+						for _, choice := range chunk.Choices {
+							content += choice.Message.Content
+							if choice.FinishReason != "" {
+								if finish != "" {
+									t.Errorf("expected a single finish reason, got %q after %q", choice.FinishReason, finish)
+								}
+								finish = choice.FinishReason
+							}
+						}
+						if chunk.Usage != (models.MessageUsage{}) {
+							usage = chunk.Usage
+						}
+					case err, ok := <-errs:
+						if !ok {
+							errs = nil
+						} else {
+							t.Fatalf("unexpected error: %v", err)
+						}
+					}
+				}
+			} else {
+				resp, err := client.CreateMessage(context.Background(), req, api.WithContinuation(100))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				content, finish, usage = resp.Choices[0].Message.Content, resp.Choices[0].FinishReason, resp.Usage
+			}
+
+			if content != "Once upon a time the end." || finish != "end_turn" {
+				t.Errorf("unexpected stitched output %q with finish reason %q", content, finish)
+			}
+			if usage.CompletionTokens != 18 {
+				t.Errorf("expected summed output tokens 18, got %d", usage.CompletionTokens)
+			}
+
+			reqs := server.MessageRequests()
+			if len(reqs) != 3 {
+				t.Fatalf("expected 3 requests, got %d", len(reqs))
+			}
+			prefill := reqs[2].Messages[len(reqs[2].Messages)-1]
+			if prefill.Role != models.AssistantRole || prefill.Content != "Once upon a time" {
+				t.Errorf("unexpected prefill %+v", prefill)
+			}
+			if len(reqs[2].Messages) != 2 {
+				t.Errorf("expected the prefill to be extended in place, got %d messages", len(reqs[2].Messages))
+			}
+		})
+	}
+}
+
+func TestContinuationBudget(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
+	client.SetBaseURL(server.URL)
+
+	server.Enqueue(
+		truncated("one ", "max_tokens", 10),
+		truncated("two ", "max_tokens", 10),
+		truncated("three", "end_turn", 10),
+	)
+	req := &models.MessageRequest{
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Count"}},
+		MaxTokens: 10,
+	}
+	resp, err := client.CreateMessage(context.Background(), req, api.WithContinuation(15))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "one two " || resp.Choices[0].FinishReason != "max_tokens" {
+		t.Errorf("expected to stop at the budget, got %q (%s)", resp.Choices[0].Message.Content, resp.Choices[0].FinishReason)
+	}
+	if got := server.LastMessageRequest().MaxTokens; got != 5 {
+		t.Errorf("expected the last request to ask for the remaining 5 tokens, got %d", got)
+	}
+}
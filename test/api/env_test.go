@@ -0,0 +1,92 @@
+// test/api/env_test.go
+package api_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+
+	t.Setenv(api.EnvAPIKey, "")
+	t.Setenv(api.EnvAuthToken, "token")
+	t.Setenv(api.EnvBaseURL, server.URL+"/")
+	t.Setenv(api.EnvTimeout, "5")
+	t.Setenv(api.EnvMaxRetries, "0")
+	t.Setenv(api.EnvModel, "claude-env")
+	t.Setenv(api.EnvBeta, "beta-one, beta-two")
+	t.Setenv(api.EnvProxy, "")
+
+	client, err := api.NewClientFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.BaseURL != server.URL {
+		t.Errorf("expected base URL %q, got %q", server.URL, client.BaseURL)
+	}
+	if client.HTTPClient.Timeout.Seconds() != 5 {
+		t.Errorf("expected 5s timeout, got %v", client.HTTPClient.Timeout)
+	}
+
+	message := &models.MessageRequest{
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+		MaxTokens: 16,
+	}
+	_, err = client.CreateMessage(context.Background(), message)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := server.Requests()[0]
+	if got := req.Header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("expected bearer token, got %q", got)
+	}
+	if got := req.Header.Get("X-API-Key"); got != "" {
+		t.Errorf("expected no API key header, got %q", got)
+	}
+	if got := req.Header.Get("anthropic-beta"); got != "beta-one,beta-two" {
+		t.Errorf("unexpected beta header %q", got)
+	}
+	if got := server.LastMessageRequest().Model; got != "claude-env" {
+		t.Errorf("expected default model, got %q", got)
+	}
+	if message.Model != "" {
+		t.Errorf("expected the caller's request to keep an empty model, got %q", message.Model)
+	}
+}
+
+func TestNewClientFromEnvErrors(t *testing.T) {
+	t.Setenv(api.EnvAPIKey, "")
+	t.Setenv(api.EnvAuthToken, "")
+	t.Setenv(api.EnvBaseURL, "")
+	t.Setenv(api.EnvTimeout, "")
+	t.Setenv(api.EnvMaxRetries, "")
+	t.Setenv(api.EnvProxy, "")
+
+	if _, err := api.NewClientFromEnv(); err == nil || !strings.Contains(err.Error(), api.EnvAPIKey) {
+		t.Errorf("expected missing credentials error, got %v", err)
+	}
+	if client, err := api.NewClientFromEnv(api.WithAPIKey("key")); err != nil || client.APIKey != "key" {
+		t.Errorf("expected option to provide the key, got %v", err)
+	}
+
+	t.Setenv(api.EnvAPIKey, "key")
+	t.Setenv(api.EnvBaseURL, "api.example.com")
+	t.Setenv(api.EnvTimeout, "soon")
+	t.Setenv(api.EnvMaxRetries, "-1")
+	_, err := api.NewClientFromEnv()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, name := range []string{api.EnvBaseURL, api.EnvTimeout, api.EnvMaxRetries} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to mention %s, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// test/api/logging_test.go
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestSlogLoggerRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("request-id", "req_abc")
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{ID: "msg-1"})
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		api.WithSlogLogger(logger, api.DefaultRedactionPolicy),
+	)
+	client.SetBaseURL(server.URL)
+
+	req := &models.MessageRequest{
+		Messages: []models.Message{{Role: models.UserRole, Content: "my secret prompt sk-ant-api03-abcdef"}},
+	}
+	if _, err := client.CreateMessage(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, leaked := range []string{"my secret prompt", "sk-ant-api03-abcdef"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("expected %q to be redacted from log output:\n%s", leaked, out)
+		}
+	}
+	for _, field := range []string{`"request_id":"req_abc"`, `"status":200`, `"latency":`} {
+		if !strings.Contains(out, field) {
+			t.Errorf("expected structured field %s in log output:\n%s", field, out)
+		}
+	}
+}
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "DEBUG "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.lines = append(l.lines, "INFO "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "WARN "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "ERROR "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Fatalf(format string, args ...interface{}) {
+	l.lines = append(l.lines, "FATAL "+fmt.Sprintf(format, args...))
+}
+
+func TestLogLevelFiltering(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"message": "bad request"}`))
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		api.WithLogger(logger),
+		api.WithLogLevel(api.LogLevelError),
+	)
+	client.SetBaseURL(server.URL)
+
+	if _, err := client.CreateMessage(context.Background(), &models.MessageRequest{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(logger.lines) == 0 {
+		t.Fatal("expected the API error to be logged")
+	}
+	for _, line := range logger.lines {
+		if !strings.HasPrefix(line, "ERROR ") {
+			t.Errorf("expected only error lines, got %q", line)
+		}
+	}
+}
@@ -2,17 +2,84 @@
 package api_test
 
 import (
-    "bytes"
-    "context"
-    "encoding/json"
-    "io/ioutil"
-    "net/http"
-    "net/http/httptest"
-    "testing"
-
-    "github.com/Aanthord/anthropic-go/pkg/api"
-    "github.com/Aanthord/anthropic-go/pkg/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
 )
 
-// ... CreateMessage and StreamMessages tests similar to Completions ...
+// namedStream is a message stream whose events are named after their type, as
+// the API sends them, with a ping and a comment in between.
+const namedStream = `event: message_start
+data: {"id":"msg_1","model":"claude-3-5-haiku-20241022"}
+
+: keep-alive
+
+event: content_block_delta
+data: {"choices":[{"index":0,"message":{"role":"assistant","content":"Hello "}}]}
+
+event: ping
+data: {"type": "ping"}
+
+event: content_block_delta
+data: {"choices":[{"index":0,"message":{"role":"assistant","content":"world"}}]}
+
+event: message_delta
+data: {"choices":[{"index":0,"finish_reason":"end_turn"}],"usage":{"prompt_tokens":3,"completion_tokens":2}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestStreamMessagesNamedEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(namedStream))
+	}))
+	defer server.Close()
+
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
+	client.SetBaseURL(server.URL)
+
+	stream, errs := client.StreamMessages(context.Background(), &models.MessageRequest{
+		Model:     "claude-3-5-haiku-20241022",
+		MaxTokens: 16,
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+	})
+	var chunks []models.MessageResponse
+	for stream != nil || errs != nil {
+		select {
+		case chunk, ok := <-stream:
+			if !ok {
+				stream = nil
+				continue
+			}
+			chunks = append(chunks, chunk)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
 
+	if len(chunks) != 5 {
+		t.Fatalf("expected every event but the ping to be decoded, got %d chunks", len(chunks))
+	}
+	var assembled models.MessageResponse
+	for _, chunk := range chunks {
+		streams.AccumulateChunk(&assembled, chunk)
+	}
+	if assembled.ID != "msg_1" {
+		t.Errorf("expected the message_start event to be decoded, got ID %q", assembled.ID)
+	}
+	if text := assembled.Text(); text != "Hello world" || assembled.Choices[0].FinishReason != "end_turn" {
+		t.Errorf("unexpected assembled message %+v", assembled)
+	}
+}
@@ -0,0 +1,126 @@
+// test/api/middleware_test.go
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "outer,inner" {
+			t.Errorf("expected X-Trace 'outer,inner', got %q", r.Header.Get("X-Trace"))
+		}
+		var req models.MessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if req.MaxTokens != 42 {
+			t.Errorf("expected mutated max_tokens 42, got %d", req.MaxTokens)
+		}
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{ID: "msg-1"})
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) api.Middleware {
+		return func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+			order = append(order, name+" in")
+			if prev := call.Header.Get("X-Trace"); prev != "" {
+				call.Header.Set("X-Trace", prev+","+name)
+			} else {
+				call.Header.Set("X-Trace", name)
+			}
+			result, err := next(ctx, call)
+			order = append(order, name+" out")
+			return result, err
+		}
+	}
+	mutate := func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+		if req := call.MessageRequest(); req != nil {
+			req.MaxTokens = 42
+		}
+		result, err := next(ctx, call)
+		if err == nil && result.MessageResponse() == nil {
+			t.Error("expected decoded message response in result")
+		}
+		return result, err
+	}
+
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		api.WithMiddleware(trace("outer"), trace("inner"), mutate),
+	)
+	client.SetBaseURL(server.URL)
+
+	resp, err := client.CreateMessage(context.Background(), &models.MessageRequest{MaxTokens: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ID != "msg-1" {
+		t.Errorf("expected ID 'msg-1', got %q", resp.ID)
+	}
+	expected := []string{"outer in", "inner in", "inner out", "outer out"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected order %v, got %v", expected, order)
+	}
+}
+
+func TestMiddlewareShortCircuitAndRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "expired"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{ID: "msg-2"})
+	}))
+	defer server.Close()
+
+	cached := &models.MessageResponse{ID: "cached"}
+	shortCircuit := func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+		if req := call.MessageRequest(); req != nil && req.MaxTokens == 1 {
+			return &api.Result{Value: cached}, nil
+		}
+		return next(ctx, call)
+	}
+	refresh := func(ctx context.Context, call *api.Call, next api.Handler) (*api.Result, error) {
+		result, err := next(ctx, call)
+		if result != nil && result.HTTPResponse != nil && result.HTTPResponse.StatusCode == http.StatusUnauthorized {
+			call.Header.Set("X-Refreshed", "true")
+			return next(ctx, call)
+		}
+		return result, err
+	}
+
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		api.WithMiddleware(shortCircuit, refresh),
+	)
+	client.SetBaseURL(server.URL)
+
+	resp, err := client.CreateMessage(context.Background(), &models.MessageRequest{MaxTokens: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != cached || requests != 0 {
+		t.Errorf("expected short-circuited response without requests, got %q after %d requests", resp.ID, requests)
+	}
+
+	resp, err = client.CreateMessage(context.Background(), &models.MessageRequest{MaxTokens: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ID != "msg-2" || requests != 2 {
+		t.Errorf("expected retried response 'msg-2' after 2 requests, got %q after %d", resp.ID, requests)
+	}
+}
@@ -0,0 +1,99 @@
+// test/api/options_test.go
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestRequestOptions(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	other := anthropictest.NewServer()
+	defer other.Close()
+
+	client := api.NewClient("api-key", api.WithBetas("client-beta"))
+	client.SetBaseURL(server.URL)
+
+	req := &models.MessageRequest{
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+		MaxTokens: 16,
+	}
+	_, err := client.CreateMessage(context.Background(), req,
+		api.WithHeader("X-Custom", "value"),
+		api.WithQuery("debug", "1"),
+		api.WithIdempotencyKey("key-1"),
+		api.WithRequestBetas("call-beta"),
+		api.WithExtraBody("top_k", 5),
+		api.WithExtraBody("max_tokens", 32),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := server.Requests()[0]
+	if got.Header.Get("X-Custom") != "value" || got.Header.Get("Idempotency-Key") != "key-1" {
+		t.Errorf("missing request headers: %v", got.Header)
+	}
+	if beta := got.Header.Get("anthropic-beta"); beta != "client-beta,call-beta" {
+		t.Errorf("unexpected beta header %q", beta)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(got.Body, &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body["top_k"] != float64(5) || body["max_tokens"] != float64(32) {
+		t.Errorf("expected extra body fields to be merged, got %v", body)
+	}
+	if len(body["messages"].([]interface{})) != 1 {
+		t.Errorf("expected modeled fields to be kept, got %v", body)
+	}
+
+	if _, err := client.ListModels(context.Background(), "", 10, api.WithRequestBaseURL(other.URL), api.WithQuery("after", "x")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other.AssertRequestCount(t, "/v1/models", 1)
+	server.AssertRequestCount(t, "/v1/models", 0)
+
+	server.Enqueue(anthropictest.Response{Message: anthropictest.TextMessage("slow"), Latency: 200 * time.Millisecond})
+	_, err = client.CreateMessage(context.Background(), req, api.WithRequestTimeout(20*time.Millisecond), api.WithRequestMaxRetries(0))
+	if err == nil {
+		t.Error("expected the request timeout to be enforced")
+	}
+}
+
+// noAttempt is a retrier that never calls the API.
+type noAttempt struct{}
+
+func (noAttempt) Do(fn func() (*http.Response, error)) (*http.Response, error) {
+	return nil, nil
+}
+
+func TestRequestRetriesWithoutAttempts(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	server.EnqueueText("Hello")
+
+	client := api.NewClient("api-key")
+	client.SetBaseURL(server.URL)
+	req := &models.MessageRequest{
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+		MaxTokens: 16,
+	}
+
+	if _, err := client.CreateMessage(context.Background(), req, api.WithRequestMaxRetries(-1)); err != nil {
+		t.Fatalf("expected a negative retry count to make one attempt, got %v", err)
+	}
+	server.AssertRequestCount(t, "/v1/messages", 1)
+
+	if _, err := client.CreateMessage(context.Background(), req, api.WithRequestRetrier(noAttempt{})); err == nil {
+		t.Error("expected an error from a retrier that makes no attempt")
+	}
+}
@@ -0,0 +1,52 @@
+// test/api/response_test.go
+package api_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestResponseMetadata(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	reset := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	server.Header.Set("anthropic-ratelimit-requests-limit", "50")
+	server.Header.Set("anthropic-ratelimit-requests-remaining", "49")
+	server.Header.Set("anthropic-ratelimit-requests-reset", reset.Format(time.RFC3339))
+	server.Header.Set("anthropic-ratelimit-output-tokens-remaining", "7000")
+
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
+	client.SetBaseURL(server.URL)
+	req := &models.MessageRequest{
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+		MaxTokens: 16,
+	}
+
+	var metadata api.ResponseMetadata
+	if _, err := client.CreateMessage(context.Background(), req, api.WithResponseMetadata(&metadata)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metadata.StatusCode != 200 || metadata.RequestID == "" || metadata.Attempts != 1 {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	if metadata.Requests.Limit != 50 || metadata.Requests.Remaining != 49 || !metadata.Requests.Reset.Equal(reset) {
+		t.Errorf("unexpected request limits %+v", metadata.Requests)
+	}
+	if metadata.OutputTokens.Remaining != 7000 {
+		t.Errorf("unexpected output token limits %+v", metadata.OutputTokens)
+	}
+
+	server.EnqueueError(anthropictest.RateLimitError())
+	var failed api.ResponseMetadata
+	if _, err := client.CreateMessage(context.Background(), req, api.WithResponseMetadata(&failed)); err == nil {
+		t.Fatal("expected rate limit error")
+	}
+	if failed.StatusCode != 429 || failed.RetryAfter != time.Second || failed.RequestID == "" {
+		t.Errorf("expected metadata of the failed response, got %+v", failed)
+	}
+}
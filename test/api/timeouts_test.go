@@ -0,0 +1,129 @@
+// test/api/timeouts_test.go
+package api_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// drainStream reads a stream to the end and returns its text and first error.
+func drainStream(stream <-chan models.MessageResponse, errs <-chan error) (string, error) {
+	var content string
+	var firstErr error
+	for stream != nil || errs != nil {
+		select {
+		case chunk, ok := <-stream:
+			if !ok {
+				stream = nil
+				continue
+			}
+			for _, choice := range chunk.Choices {
+				content += choice.Message.Content
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+			} else if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return content, firstErr
+}
+
+func TestStreamTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		response anthropictest.Response
+		ping     time.Duration
+		timeouts api.StreamTimeouts
+		content  string
+		err      string
+	}{
+		{
+			name:     "connect",
+			response: anthropictest.Response{Message: anthropictest.TextMessage("late"), Latency: 300 * time.Millisecond},
+			timeouts: api.StreamTimeouts{Connect: 30 * time.Millisecond},
+			err:      "connect timeout",
+		},
+		{
+			name:     "first event",
+			response: anthropictest.Response{Message: anthropictest.TextMessage("slow start"), ChunkDelay: 300 * time.Millisecond},
+			timeouts: api.StreamTimeouts{FirstEvent: 30 * time.Millisecond, Idle: time.Second},
+			err:      "first event timeout",
+		},
+		{
+			name:     "idle",
+			response: anthropictest.Response{Message: anthropictest.TextMessage("stalls"), ChunkDelay: 100 * time.Millisecond},
+			timeouts: api.StreamTimeouts{FirstEvent: time.Second, Idle: 30 * time.Millisecond},
+			err:      "idle timeout",
+		},
+		{
+			name:     "pings keep the stream alive",
+			response: anthropictest.Response{Message: anthropictest.TextMessage("kept alive"), ChunkDelay: 60 * time.Millisecond},
+			ping:     10 * time.Millisecond,
+			timeouts: api.StreamTimeouts{FirstEvent: 40 * time.Millisecond, Idle: 40 * time.Millisecond},
+			content:  "kept alive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := anthropictest.NewServer()
+			defer server.Close()
+			server.ChunkSize = 4
+			server.PingInterval = tt.ping
+			server.Enqueue(tt.response)
+			client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
+			client.SetBaseURL(server.URL)
+
+			req := &models.MessageRequest{
+				Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+				MaxTokens: 16,
+			}
+			content, err := drainStream(client.StreamMessages(context.Background(), req,
+				api.WithRequestStreamTimeouts(tt.timeouts), api.WithRequestMaxRetries(0)))
+			if tt.err == "" {
+				if err != nil || content != tt.content {
+					t.Errorf("expected %q without error, got %q, %v", tt.content, content, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected %q error, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestTimeoutScalesWithMaxTokens(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+
+	httpClient := server.Client()
+	httpClient.Timeout = 50 * time.Millisecond
+	req := &models.MessageRequest{
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+		MaxTokens: 500,
+	}
+
+	client := api.NewClient("api-key", api.WithHTTPClient(httpClient), api.WithTimeoutPerOutputToken(time.Millisecond))
+	client.SetBaseURL(server.URL)
+	server.Enqueue(anthropictest.Response{Message: anthropictest.TextMessage("long answer"), Latency: 150 * time.Millisecond})
+	if _, err := client.CreateMessage(context.Background(), req); err != nil {
+		t.Errorf("expected the scaled timeout to allow the call, got %v", err)
+	}
+
+	client = api.NewClient("api-key", api.WithHTTPClient(httpClient), api.WithTimeoutPerOutputToken(0))
+	client.SetBaseURL(server.URL)
+	server.Enqueue(anthropictest.Response{Message: anthropictest.TextMessage("long answer"), Latency: 150 * time.Millisecond})
+	if _, err := client.CreateMessage(context.Background(), req, api.WithRequestMaxRetries(0)); err == nil {
+		t.Error("expected the HTTP client timeout without scaling")
+	}
+}
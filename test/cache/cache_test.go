@@ -0,0 +1,216 @@
+// test/cache/cache_test.go
+package cache_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/cache"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func newServer(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{
+			ID: "msg-1",
+			Choices: []models.MessageChoice{{
+				Message:      models.Message{Role: models.AssistantRole, Content: "Hello there"},
+				FinishReason: "end_turn",
+			}},
+			Usage: models.MessageUsage{PromptTokens: 3, CompletionTokens: 2},
+		})
+	}))
+}
+
+func TestCacheBackends(t *testing.T) {
+	fileBackend, err := cache.NewFileBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create file backend: %v", err)
+	}
+	backends := map[string]cache.Backend{
+		"memory": cache.NewMemoryBackend(10),
+		"file":   fileBackend,
+	}
+
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			requests := 0
+			server := newServer(t, &requests)
+			defer server.Close()
+
+			client := api.NewClient("api-key", api.WithHTTPClient(server.Client()), cache.WithCache(cache.New(backend, time.Hour)))
+			client.SetBaseURL(server.URL)
+
+			newRequest := func() *models.MessageRequest {
+				return &models.MessageRequest{
+					Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+					MaxTokens: 16,
+					Metadata:  &models.Metadata{UserID: name},
+				}
+			}
+
+			for i := 0; i < 3; i++ {
+				resp, err := client.CreateMessage(context.Background(), newRequest())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if resp.Choices[0].Message.Content != "Hello there" {
+					t.Errorf("unexpected content %q", resp.Choices[0].Message.Content)
+				}
+			}
+			if requests != 1 {
+				t.Errorf("expected 1 request, got %d", requests)
+			}
+
+			if _, err := client.CreateMessage(cache.Bypass(context.Background()), newRequest()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if requests != 2 {
+				t.Errorf("expected bypass to reach the server, got %d requests", requests)
+			}
+
+			stream, errs := client.StreamMessages(context.Background(), newRequest())
+			var content string
+			var usage models.MessageUsage
+			for chunk := range stream {
+				for _, choice := range chunk.Choices {
+					content += choice.Message.Content
+				}
+				if chunk.Usage != (models.MessageUsage{}) {
+					usage = chunk.Usage
+				}
+			}
+			for err := range errs {
+				t.Errorf("unexpected stream error: %v", err)
+			}
+			if content != "Hello there" || usage.CompletionTokens != 2 {
+				t.Errorf("unexpected replayed stream: content %q, usage %+v", content, usage)
+			}
+			if requests != 2 {
+				t.Errorf("expected replayed stream without request, got %d requests", requests)
+			}
+		})
+	}
+}
+
+func TestKeyIgnoresStreamAndMetadata(t *testing.T) {
+	a := &models.MessageRequest{Messages: []models.Message{{Role: models.UserRole, Content: "Hi"}}}
+	b := &models.MessageRequest{Messages: []models.Message{{Role: models.UserRole, Content: "Hi"}}, Stream: true, Metadata: &models.Metadata{UserID: "u"}}
+	c := &models.MessageRequest{Messages: []models.Message{{Role: models.UserRole, Content: "Hi"}}, Temperature: 0.5}
+
+	keyA, _ := cache.Key(a)
+	keyB, _ := cache.Key(b)
+	keyC, _ := cache.Key(c)
+	if keyA != keyB {
+		t.Error("expected stream flag and metadata to be ignored")
+	}
+	if keyA == keyC {
+		t.Error("expected sampling parameters to change the key")
+	}
+}
+
+func TestMemoryBackendEvictsLeastRecentlyUsed(t *testing.T) {
+	backend := cache.NewMemoryBackend(2)
+	_ = backend.Set("a", &models.MessageResponse{ID: "a"}, 0)
+	_ = backend.Set("b", &models.MessageResponse{ID: "b"}, 0)
+	if _, ok, _ := backend.Get("a"); !ok {
+		t.Fatal("expected entry a")
+	}
+	_ = backend.Set("c", &models.MessageResponse{ID: "c"}, 0)
+
+	if _, ok, _ := backend.Get("b"); ok {
+		t.Error("expected least recently used entry b to be evicted")
+	}
+	if _, ok, _ := backend.Get("a"); !ok {
+		t.Error("expected entry a to survive")
+	}
+}
+
+func TestCacheSkipsFailedStreams(t *testing.T) {
+	backend := cache.NewMemoryBackend(10)
+	mw := cache.New(backend, time.Hour).Middleware()
+	req := &models.MessageRequest{Messages: []models.Message{{Role: models.UserRole, Content: "Hi"}}, Stream: true}
+
+	tests := map[string]struct {
+		chunks []models.MessageResponse
+		err    error
+	}{
+		"error midway": {
+			chunks: []models.MessageResponse{{Choices: []models.MessageChoice{{Message: models.Message{Content: "Hel"}}}}},
+			err:    errors.New("connection reset"),
+		},
+		"no finish reason": {
+			chunks: []models.MessageResponse{{Choices: []models.MessageChoice{{Message: models.Message{Content: "Hel"}}}}},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			next := func(ctx context.Context, call *api.Call) (*api.Result, error) {
+				stream := make(chan models.MessageResponse)
+				errs := make(chan error)
+				go func() {
+					defer close(stream)
+					defer close(errs)
+					for _, chunk := range tt.chunks {
+						stream <- chunk
+					}
+					if tt.err != nil {
+						errs <- tt.err
+					}
+				}()
+				return &api.Result{Stream: stream, StreamErrors: errs}, nil
+			}
+			result, err := mw(context.Background(), &api.Call{Operation: "StreamMessages", Params: req, Stream: true}, next)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var streamErrs []error
+			stream, errs := result.Stream, result.StreamErrors
+			for stream != nil || errs != nil {
+				select {
+				case _, ok := <-stream:
+					if !ok {
+						stream = nil
+					}
+				case err, ok := <-errs:
+					if !ok {
+						errs = nil
+						continue
+					}
+					streamErrs = append(streamErrs, err)
+				}
+			}
+			if (tt.err != nil) != (len(streamErrs) == 1) {
+				t.Errorf("expected the stream error to be forwarded, got %v", streamErrs)
+			}
+			if backend.Len() != 0 {
+				t.Error("expected an incomplete stream not to be cached")
+			}
+		})
+	}
+}
+
+func TestMemoryBackendCopies(t *testing.T) {
+	backend := cache.NewMemoryBackend(10)
+	resp := &models.MessageResponse{Choices: []models.MessageChoice{{Message: models.Message{
+		Blocks: []models.ContentBlock{{Type: models.TextBlock, Text: "Hello"}},
+	}}}}
+	_ = backend.Set("a", resp, 0)
+	resp.Choices[0].Message.Blocks[0].Text = "changed by the caller"
+
+	cached, _, _ := backend.Get("a")
+	cached.Choices[0].Message.Blocks[0].Text = "changed by a reader"
+	cached.Choices = nil
+
+	if again, _, _ := backend.Get("a"); again.Choices[0].Message.Blocks[0].Text != "Hello" {
+		t.Errorf("expected the cached entry to be isolated, got %+v", again.Choices)
+	}
+}
@@ -0,0 +1,221 @@
+// test/conversation/conversation_test.go
+package conversation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/conversation"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestConversationTurnChecks(t *testing.T) {
+	conv := conversation.New(anthropictest.NewFake(), models.MessageRequest{MaxTokens: 64})
+
+	if err := conv.AddAssistant("Hello"); !errors.Is(err, conversation.ErrFirstTurn) {
+		t.Errorf("expected ErrFirstTurn, got %v", err)
+	}
+	if err := conv.AddMessage(models.Message{Role: models.SystemRole, Content: "Be brief"}); !errors.Is(err, conversation.ErrInvalidRole) {
+		t.Errorf("expected ErrInvalidRole, got %v", err)
+	}
+	if err := conv.AddUser(""); !errors.Is(err, conversation.ErrEmptyTurn) {
+		t.Errorf("expected ErrEmptyTurn, got %v", err)
+	}
+	if _, err := conv.Request(); !errors.Is(err, conversation.ErrNoTurns) {
+		t.Errorf("expected ErrNoTurns, got %v", err)
+	}
+	if err := conv.AddUser("What is the weather?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := conv.AddUser("In Paris"); !errors.Is(err, conversation.ErrRoleAlternation) {
+		t.Errorf("expected ErrRoleAlternation, got %v", err)
+	}
+
+	err := conv.AddMessage(models.Message{Role: models.AssistantRole, Blocks: []models.ContentBlock{
+		{Type: models.ToolUseBlock, ID: "toolu_1", Name: "weather"},
+		{Type: models.ToolUseBlock, ID: "toolu_2", Name: "time"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := conv.AddUser("Never mind"); !errors.Is(err, conversation.ErrMissingToolResult) {
+		t.Errorf("expected ErrMissingToolResult, got %v", err)
+	}
+	if err := conv.AddToolResult("toolu_9", "?", true); !errors.Is(err, conversation.ErrUnknownToolUse) {
+		t.Errorf("expected ErrUnknownToolUse, got %v", err)
+	}
+	if err := conv.AddToolResult("toolu_1", "Sunny", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := conv.Request(); !errors.Is(err, conversation.ErrMissingToolResult) {
+		t.Errorf("expected ErrMissingToolResult with toolu_2 unanswered, got %v", err)
+	}
+	if pending := conv.PendingToolUses(); len(pending) != 1 || pending[0].ID != "toolu_2" {
+		t.Errorf("expected toolu_2 pending, got %+v", pending)
+	}
+	if err := conv.AddToolResult("toolu_2", "Noon", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	turns := conv.Turns()
+	if len(turns) != 3 || len(turns[2].Message.Blocks) != 2 {
+		t.Fatalf("expected both tool results in one user turn, got %+v", turns)
+	}
+	if _, err := conv.Request(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConversationSend(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()))
+	client.SetBaseURL(server.URL)
+
+	conv := conversation.New(client, models.MessageRequest{MaxTokens: 64})
+	conv.System = "Answer briefly."
+	conv.Tools = []models.Tool{{Name: "weather", InputSchema: []byte(`{"type":"object"}`)}}
+
+	server.EnqueueToolUse("toolu_1", "weather", map[string]string{"city": "Paris"})
+	server.EnqueueText("It is sunny.")
+
+	if err := conv.AddUser("Weather in Paris?"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := conv.Send(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pending := conv.PendingToolUses()
+	if len(pending) != 1 || pending[0].ID != "toolu_1" {
+		t.Fatalf("expected the tool use to be pending, got %+v", pending)
+	}
+	if err := conv.AddToolResult("toolu_1", "Sunny", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := conv.Send(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "It is sunny." {
+		t.Errorf("unexpected reply %q", resp.Choices[0].Message.Content)
+	}
+
+	last := server.LastMessageRequest()
+	if last.System != "Answer briefly." || len(last.Tools) != 1 || len(last.Messages) != 3 {
+		t.Errorf("unexpected request %+v", last)
+	}
+
+	turns := conv.Turns()
+	if len(turns) != 4 {
+		t.Fatalf("expected 4 turns, got %d", len(turns))
+	}
+	reply := turns[3]
+	if reply.Message.Role != models.AssistantRole || reply.RequestID == "" || reply.StopReason == "" || reply.Usage.CompletionTokens == 0 {
+		t.Errorf("expected the reply to record its response, got %+v", reply)
+	}
+	usage := conv.Usage()
+	if usage.CompletionTokens != turns[1].Usage.CompletionTokens+turns[3].Usage.CompletionTokens {
+		t.Errorf("unexpected total usage %+v", usage)
+	}
+}
+
+func TestConversationSendError(t *testing.T) {
+	fake := anthropictest.NewFake()
+	fake.EnqueueError(anthropictest.OverloadedError())
+	fake.EnqueueText("Hi there")
+
+	conv := conversation.New(fake, models.MessageRequest{MaxTokens: 64})
+	if err := conv.AddUser("Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := conv.Send(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := len(conv.Turns()); n != 1 {
+		t.Errorf("expected a failed send to leave the history unchanged, got %d turns", n)
+	}
+	if _, err := conv.Send(context.Background()); err != nil {
+		t.Errorf("expected the retry to succeed, got %v", err)
+	}
+}
+
+func TestConversationConcurrentSend(t *testing.T) {
+	fake := anthropictest.NewFake()
+	fake.Enqueue(anthropictest.Response{Message: anthropictest.TextMessage("slow"), Latency: 200 * time.Millisecond})
+	fake.EnqueueText("fast")
+
+	conv := conversation.New(fake, models.MessageRequest{MaxTokens: 64})
+	if err := conv.AddUser("Hi"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slow := make(chan error)
+	go func() {
+		_, err := conv.Send(context.Background())
+		slow <- err
+	}()
+	for len(fake.Calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := conv.Send(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := <-slow; !errors.Is(err, conversation.ErrConversationChanged) {
+		t.Errorf("expected ErrConversationChanged for the overtaken send, got %v", err)
+	}
+	turns := conv.Turns()
+	if len(turns) != 2 || turns[1].Message.Content != "fast" {
+		t.Errorf("expected only the first reply to be recorded, got %+v", turns)
+	}
+}
+
+func TestConversationStream(t *testing.T) {
+	fake := anthropictest.NewFake()
+	fake.ChunkSize = 2
+	fake.EnqueueText("a time.")
+
+	conv := conversation.New(fake, models.MessageRequest{MaxTokens: 64})
+	if err := conv.AddUser("Tell a story"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := conv.AddAssistant("Once upon"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var content string
+	stream, errs := conv.Stream(context.Background())
+	for stream != nil || errs != nil {
+		select {
+		case chunk, ok := <-stream:
+			if !ok {
+				stream = nil
+				continue
+			}
+			for _, choice := range chunk.Choices {
+				content += choice.Message.Content
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+			} else {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+	if content != "a time." {
+		t.Errorf("unexpected streamed content %q", content)
+	}
+
+	turns := conv.Turns()
+	if len(turns) != 2 {
+		t.Fatalf("expected the reply to extend the prefill, got %d turns", len(turns))
+	}
+	if got := turns[1].Message.Content; got != "Once upona time." {
+		t.Errorf("unexpected assistant turn %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+// test/conversation/store_test.go
+package conversation_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/conversation"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) conversation.Store{
+		"file": func(t *testing.T) conversation.Store {
+			store, err := conversation.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return store
+		},
+		"bolt": func(t *testing.T) conversation.Store {
+			store, err := conversation.OpenBoltStore(filepath.Join(t.TempDir(), "conversations.db"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			fake := anthropictest.NewFake()
+			fake.EnqueueToolUse("toolu_1", "lookup", map[string]string{"q": "otters"})
+			fake.EnqueueText("Otters hold hands while sleeping.")
+
+			conv := conversation.New(fake, models.MessageRequest{MaxTokens: 64})
+			conv.System = "Be brief."
+			conv.Metadata = map[string]string{"user": "alice"}
+			if err := conv.AddUser("Tell me about otters"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := conv.Send(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := conv.AddToolResult("toolu_1", "Sea otters sleep holding hands", false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := conv.Send(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := conv.Save(store); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if conv.ID == "" {
+				t.Fatal("expected Save to assign an ID")
+			}
+
+			other := conversation.New(fake, models.MessageRequest{MaxTokens: 64})
+			other.Metadata = map[string]string{"user": "bob"}
+			if err := other.AddUser("Hello"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := other.Save(store); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			restored, err := conversation.Load(fake, store, conv.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			turns := restored.Turns()
+			if len(turns) != 4 || restored.System != "Be brief." || restored.Metadata["user"] != "alice" {
+				t.Fatalf("unexpected restored conversation with %d turns: %+v", len(turns), restored.Record())
+			}
+			if turns[1].Message.Blocks[0].ID != "toolu_1" || turns[2].Message.Blocks[0].ToolUseID != "toolu_1" {
+				t.Errorf("expected content blocks to round-trip, got %+v", turns[1:3])
+			}
+			if restored.Usage() != conv.Usage() || turns[3].ResponseID == "" {
+				t.Errorf("expected usage and response details to round-trip, got %+v", turns[3])
+			}
+
+			record, err := store.Load(conv.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			req := record.Request()
+			if len(req.Messages) != 4 || req.System != "Be brief." || req.MaxTokens != 64 {
+				t.Errorf("unexpected request %+v", req)
+			}
+
+			records, err := store.List(conversation.Filter{Metadata: map[string]string{"user": "alice"}})
+			if err != nil || len(records) != 1 || records[0].ID != conv.ID {
+				t.Errorf("expected to find alice's conversation, got %v, %v", records, err)
+			}
+			records, err = store.List(conversation.Filter{Text: "HOLDING HANDS"})
+			if err != nil || len(records) != 1 || records[0].ID != conv.ID {
+				t.Errorf("expected to find the conversation by text, got %v, %v", records, err)
+			}
+			records, err = store.List(conversation.Filter{})
+			if err != nil || len(records) != 2 || records[0].ID != other.ID {
+				t.Errorf("expected both conversations, most recent first, got %v, %v", records, err)
+			}
+
+			if err := store.Delete(conv.ID); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := store.Load(conv.ID); !errors.Is(err, conversation.ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
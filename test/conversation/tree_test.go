@@ -0,0 +1,159 @@
+// test/conversation/tree_test.go
+package conversation_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/conversation"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func texts(turns []conversation.Turn) []string {
+	var texts []string
+	for _, turn := range turns {
+		texts = append(texts, turn.Message.Content)
+	}
+	return texts
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTreeBranching(t *testing.T) {
+	fake := anthropictest.NewFake()
+	fake.EnqueueText("Paris.")
+	fake.EnqueueText("It is Paris.")
+	fake.EnqueueText("Rome.")
+	tree := conversation.NewTree(fake, models.MessageRequest{MaxTokens: 64})
+
+	question, err := tree.AddUser("Capital of France?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tree.AddUser("Hello?"); err == nil {
+		t.Error("expected two user turns in a row to be rejected")
+	}
+	if _, err := tree.Send(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := tree.Active()
+
+	if _, err := tree.Regenerate(context.Background(), first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second := tree.Active()
+	if siblings := tree.Siblings(first); len(siblings) != 2 || siblings[1] != second {
+		t.Errorf("expected the regenerated reply to be an alternative, got %v", siblings)
+	}
+	if got := texts(tree.Turns()); !equal(got, []string{"Capital of France?", "It is Paris."}) {
+		t.Errorf("unexpected active branch %q", got)
+	}
+
+	if _, err := tree.Edit(question, models.Message{Role: models.UserRole, Content: "Capital of Italy?"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tree.Send(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	edited := tree.Active()
+	if got := texts(messagesAsTurns(fake.LastRequest().Messages)); !equal(got, []string{"Capital of Italy?"}) {
+		t.Errorf("expected only the edited branch to be sent, got %q", got)
+	}
+	if leaves := tree.Leaves(); len(leaves) != 3 {
+		t.Errorf("expected 3 branches, got %v", leaves)
+	}
+
+	comparison, err := tree.Compare(first, edited)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if comparison.ForkPoint != "" || len(comparison.Common) != 0 || !equal(texts(comparison.B), []string{"Capital of Italy?", "Rome."}) {
+		t.Errorf("unexpected comparison %+v", comparison)
+	}
+	comparison, err = tree.Compare(first, second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if comparison.ForkPoint != question || !equal(texts(comparison.A), []string{"Paris."}) {
+		t.Errorf("unexpected comparison %+v", comparison)
+	}
+
+	if err := tree.Select(question); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tree.Active() != second {
+		t.Errorf("expected selecting the question to activate its latest reply, got %s", tree.Active())
+	}
+	req, err := tree.Request()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := texts(messagesAsTurns(req.Messages)); !equal(got, []string{"Capital of France?", "It is Paris."}) {
+		t.Errorf("unexpected flattened request %q", got)
+	}
+}
+
+func messagesAsTurns(messages []models.Message) []conversation.Turn {
+	var turns []conversation.Turn
+	for _, message := range messages {
+		turns = append(turns, conversation.Turn{Message: message})
+	}
+	return turns
+}
+
+func TestTreeStore(t *testing.T) {
+	store, err := conversation.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake := anthropictest.NewFake()
+	tree := conversation.NewTree(fake, models.MessageRequest{MaxTokens: 64})
+	question, _ := tree.AddUser("Pick a color")
+	tree.AddAssistant("Blue")
+	tree.Edit(question, models.Message{Role: models.UserRole, Content: "Pick a number"})
+	tree.AddAssistant("Seven")
+	if err := tree.Save(store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restored, err := conversation.LoadTree(fake, store, tree.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restored.Active() != tree.Active() || len(restored.Leaves()) != 2 {
+		t.Errorf("expected branches and selection to round-trip, got active %s, leaves %v", restored.Active(), restored.Leaves())
+	}
+	if got := texts(restored.Turns()); !equal(got, []string{"Pick a number", "Seven"}) {
+		t.Errorf("unexpected restored branch %q", got)
+	}
+
+	linear, err := conversation.Load(fake, store, tree.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := texts(linear.Turns()); !equal(got, []string{"Pick a number", "Seven"}) {
+		t.Errorf("expected a linear load to see the active branch, got %q", got)
+	}
+
+	if err := linear.Save(store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fromLinear, err := conversation.LoadTree(fake, store, linear.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := texts(fromLinear.Turns()); !equal(got, []string{"Pick a number", "Seven"}) || len(fromLinear.Leaves()) != 1 {
+		t.Errorf("expected a linear record to load as one branch, got %q", got)
+	}
+}
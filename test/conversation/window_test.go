@@ -0,0 +1,167 @@
+// test/conversation/window_test.go
+package conversation_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/conversation"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+// history returns a request of n question/answer exchanges, with a tool call
+// in the second exchange, each exchange worth about 50 estimated tokens.
+func history(n int) *models.MessageRequest {
+	req := &models.MessageRequest{MaxTokens: 64}
+	filler := strings.Repeat("x", 96)
+	for i := 0; i < n; i++ {
+		req.Messages = append(req.Messages, models.Message{Role: models.UserRole, Content: filler})
+		if i == 1 {
+			req.Messages = append(req.Messages,
+				models.Message{Role: models.AssistantRole, Blocks: []models.ContentBlock{{Type: models.ToolUseBlock, ID: "toolu_1", Name: "lookup", Input: []byte(`{}`)}}},
+				models.Message{Role: models.UserRole, Blocks: []models.ContentBlock{{Type: models.ToolResultBlock, ToolUseID: "toolu_1", Content: strings.Repeat("r", 400)}}},
+			)
+		}
+		req.Messages = append(req.Messages, models.Message{Role: models.AssistantRole, Content: filler})
+	}
+	return req
+}
+
+// checkPairs fails the test if a tool_use and its tool_result were split up.
+func checkPairs(t *testing.T, messages []models.Message) {
+	t.Helper()
+	for i, message := range messages {
+		for _, block := range message.Blocks {
+			if block.Type == models.ToolUseBlock {
+				if i+1 >= len(messages) || len(messages[i+1].Blocks) == 0 || messages[i+1].Blocks[0].ToolUseID != block.ID {
+					t.Errorf("tool_use %s lost its tool_result", block.ID)
+				}
+			}
+			if block.Type == models.ToolResultBlock && (i == 0 || len(messages[i-1].Blocks) == 0) {
+				t.Errorf("tool_result %s lost its tool_use", block.ToolUseID)
+			}
+		}
+		if i > 0 && messages[i-1].Role == message.Role {
+			t.Errorf("messages %d and %d are both %s", i-1, i, message.Role)
+		}
+	}
+	if len(messages) > 0 && messages[0].Role != models.UserRole {
+		t.Errorf("first message is %s", messages[0].Role)
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	req := history(4)
+	manager := conversation.NewContextManager(conversation.EstimateCounter, 120)
+
+	fitted, err := manager.Fit(context.Background(), req, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkPairs(t, fitted.Messages)
+	if len(fitted.Messages) != 4 {
+		t.Fatalf("expected the pinned and last exchanges to remain, got %d messages", len(fitted.Messages))
+	}
+	if fitted.Messages[3].Content != req.Messages[len(req.Messages)-1].Content {
+		t.Error("expected the last exchange to be kept")
+	}
+	if len(req.Messages) != 10 {
+		t.Error("expected the original request to be left unchanged")
+	}
+
+	if _, err := manager.Fit(context.Background(), req, 0, 4, 6); !errors.Is(err, conversation.ErrContextTooLarge) {
+		t.Errorf("expected ErrContextTooLarge with everything pinned, got %v", err)
+	}
+}
+
+func TestFitCountsOnce(t *testing.T) {
+	calls := 0
+	counter := conversation.TokenCounterFunc(func(ctx context.Context, req *models.MessageRequest) (int, error) {
+		calls++
+		return conversation.EstimateCounter.CountTokens(ctx, req)
+	})
+	manager := conversation.NewContextManager(counter, 120)
+
+	fitted, err := manager.Fit(context.Background(), history(8))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, _ := conversation.EstimateCounter.CountTokens(context.Background(), fitted); n > 120 || len(fitted.Messages) >= 18 {
+		t.Errorf("expected exchanges to be dropped to fit, got %d tokens in %d messages", n, len(fitted.Messages))
+	}
+	if calls != 2 {
+		t.Errorf("expected one count before and one after dropping, got %d", calls)
+	}
+}
+
+func TestClearToolResults(t *testing.T) {
+	req := history(3)
+	manager := conversation.NewContextManager(conversation.EstimateCounter, 200, conversation.ClearToolResults{})
+
+	fitted, err := manager.Fit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fitted.Messages) != len(req.Messages) {
+		t.Errorf("expected no messages to be dropped, got %d", len(fitted.Messages))
+	}
+	checkPairs(t, fitted.Messages)
+	if got := fitted.Messages[4].Blocks[0].Content; got != "[tool result cleared]" {
+		t.Errorf("expected the tool result to be cleared, got %q", got)
+	}
+	if req.Messages[4].Blocks[0].Content == "[tool result cleared]" {
+		t.Error("expected the original request to be left unchanged")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	fake := anthropictest.NewFake()
+	fake.EnqueueText("They talked about x.")
+	summarize := &conversation.Summarize{Client: fake, Params: models.MessageRequest{MaxTokens: 100}}
+	manager := conversation.NewContextManager(conversation.EstimateCounter, 120, summarize)
+
+	req := history(4)
+	fitted, err := manager.Fit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkPairs(t, fitted.Messages)
+	if len(fitted.Messages) != 2 || !strings.HasPrefix(fitted.Messages[0].Content, "Summary of the earlier conversation:\nThey talked about x.") {
+		t.Errorf("expected the summary to be prepended to the last exchange, got %+v", fitted.Messages)
+	}
+	if prompt := fake.LastRequest().Messages[0].Content; !strings.Contains(prompt, "called tool lookup") {
+		t.Errorf("expected the transcript to include the tool call, got %q", prompt)
+	}
+
+	if _, err := manager.Fit(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fake.AssertCallCount(t, "CreateMessage", 1)
+}
+
+func TestConversationContext(t *testing.T) {
+	fake := anthropictest.NewFake()
+	conv := conversation.New(fake, models.MessageRequest{MaxTokens: 64})
+	conv.Context = conversation.NewContextManager(conversation.EstimateCounter, 60)
+
+	filler := strings.Repeat("y", 96)
+	for i := 0; i < 3; i++ {
+		fake.EnqueueText(filler)
+		if err := conv.AddUser(filler); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := conv.Send(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if got := len(fake.LastRequest().Messages); got != 1 {
+		t.Errorf("expected only the last exchange to be sent, got %d messages", got)
+	}
+	if got := len(conv.Turns()); got != 6 {
+		t.Errorf("expected the full history to be kept, got %d turns", got)
+	}
+}
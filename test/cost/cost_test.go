@@ -0,0 +1,255 @@
+// test/cost/cost_test.go
+package cost_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/cost"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCalculatorCost(t *testing.T) {
+	calc := cost.NewCalculator(nil)
+	usage := models.MessageUsage{
+		PromptTokens:             1_000_000,
+		CompletionTokens:         100_000,
+		CacheCreationInputTokens: 200_000,
+		CacheReadInputTokens:     500_000,
+	}
+
+	c, err := calc.Cost("claude-3-5-sonnet-20241022", usage, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !almostEqual(c.Input, 3) || !almostEqual(c.Output, 1.5) || !almostEqual(c.CacheWrite, 0.75) || !almostEqual(c.CacheRead, 0.15) {
+		t.Errorf("unexpected cost breakdown: %+v", c)
+	}
+	if !almostEqual(c.Total(), 5.4) {
+		t.Errorf("expected total 5.4, got %v", c.Total())
+	}
+
+	batch, err := calc.Cost("claude-3-5-sonnet-20241022", usage, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !almostEqual(batch.Total(), 2.7) {
+		t.Errorf("expected discounted total 2.7, got %v", batch.Total())
+	}
+
+	if _, err := calc.Cost("unknown-model", usage, false); err == nil {
+		t.Error("expected error for unknown model")
+	}
+}
+
+func TestDefaultPricesCoverModels(t *testing.T) {
+	for family := range models.MaxOutputTokens {
+		if _, ok := cost.DefaultPrices.Lookup(family + "-20990101"); !ok {
+			t.Errorf("no price for model family %s", family)
+		}
+	}
+
+	expected := map[string]float64{
+		"claude-opus-4-20250514":   15,
+		"claude-opus-4-5-20251101": 5,
+		"claude-haiku-4-5":         1,
+		"claude-3-5-haiku-latest":  0.80,
+	}
+	for model, input := range expected {
+		if p, ok := cost.DefaultPrices.Lookup(model); !ok || p.Input != input {
+			t.Errorf("expected %s input price %v, got %v", model, input, p.Input)
+		}
+	}
+}
+
+func TestGuardRejectsAndDowngrades(t *testing.T) {
+	var seenModels []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.MessageRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		seenModels = append(seenModels, req.Model)
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{
+			Usage: models.MessageUsage{PromptTokens: 1_000_000},
+		})
+	}))
+	defer server.Close()
+
+	guard := cost.NewGuard(cost.Budget{Daily: 5})
+	guard.Budgets["team-b"] = cost.Budget{Monthly: 1}
+	guard.Downgrade["claude-3-opus"] = "claude-3-haiku"
+	guard.Now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()), cost.WithBudgetGuard(guard))
+	client.SetBaseURL(server.URL)
+
+	send := func(tenant, model string) error {
+		_, err := client.CreateMessage(context.Background(), &models.MessageRequest{
+			Model:    model,
+			Metadata: &models.Metadata{UserID: tenant},
+		})
+		return err
+	}
+
+	// team-a spends $3 per sonnet call against a $5 daily budget.
+	for i := 0; i < 2; i++ {
+		if err := send("team-a", "claude-3-5-sonnet"); err != nil {
+			t.Fatalf("unexpected error on call %d: %v", i, err)
+		}
+	}
+	err := send("team-a", "claude-3-5-sonnet")
+	var exceeded cost.BudgetExceededError
+	if !errors.As(err, &exceeded) || exceeded.Period != cost.Daily || exceeded.Tenant != "team-a" {
+		t.Fatalf("expected daily budget error for team-a, got %v", err)
+	}
+
+	// team-b's opus requests are downgraded once its monthly budget is spent.
+	if err := send("team-b", "claude-3-opus"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := send("team-b", "claude-3-opus"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := seenModels[len(seenModels)-1]; got != "claude-3-haiku" {
+		t.Errorf("expected downgraded model claude-3-haiku, got %q", got)
+	}
+	req := &models.MessageRequest{Model: "claude-3-opus", Metadata: &models.Metadata{UserID: "team-b"}}
+	if _, err := client.CreateMessage(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Model != "claude-3-opus" {
+		t.Errorf("expected the caller's request to keep its model, got %q", req.Model)
+	}
+
+	spent, err := guard.Spent("team-b", cost.Monthly)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !almostEqual(spent, 15.5) {
+		t.Errorf("expected team-b monthly spend 15.5, got %v", spent)
+	}
+}
+
+// failingStore accepts the first allowed additions and fails the rest.
+type failingStore struct {
+	cost.SpendStore
+	allowed int
+}
+
+func (s *failingStore) Add(tenant, period string, amount float64) error {
+	if s.allowed > 0 {
+		s.allowed--
+		return s.SpendStore.Add(tenant, period, amount)
+	}
+	return errors.New("store unavailable")
+}
+
+func TestGuardReportsStreamRecordErrors(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	server.EnqueueText("Hello")
+
+	guard := cost.NewGuard(cost.Budget{})
+	// Let the reservation for both periods through so that settling fails.
+	guard.Store = &failingStore{SpendStore: cost.NewMemoryStore(), allowed: 2}
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()), cost.WithBudgetGuard(guard))
+	client.SetBaseURL(server.URL)
+
+	stream, errs := client.StreamMessages(context.Background(), &models.MessageRequest{
+		Model:     "claude-3-haiku",
+		MaxTokens: 16,
+		Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+	})
+	var received []error
+	for stream != nil || errs != nil {
+		select {
+		case _, ok := <-stream:
+			if !ok {
+				stream = nil
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			received = append(received, err)
+		}
+	}
+	if len(received) != 1 || !strings.Contains(received[0].Error(), "store unavailable") {
+		t.Errorf("expected the spend error on the stream, got %v", received)
+	}
+}
+
+func TestGuardReservesConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{
+			Usage: models.MessageUsage{PromptTokens: 100_000},
+		})
+	}))
+	defer server.Close()
+	defer once.Do(func() { close(release) })
+
+	guard := cost.NewGuard(cost.Budget{Daily: 1})
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()), cost.WithBudgetGuard(guard))
+	client.SetBaseURL(server.URL)
+
+	// Each call reserves a little over $0.45 of sonnet output, so only three
+	// fit in the $1 budget while they are all in flight.
+	const calls, admitted = 10, 3
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			_, err := client.CreateMessage(context.Background(), &models.MessageRequest{
+				Model:     "claude-3-5-sonnet",
+				MaxTokens: 30_000,
+				Messages:  []models.Message{{Role: models.UserRole, Content: "Hi"}},
+				Metadata:  &models.Metadata{UserID: "team-a"},
+			})
+			errs <- err
+		}()
+	}
+
+	timeout := time.After(5 * time.Second)
+	for rejected := 0; rejected < calls-admitted; rejected++ {
+		select {
+		case err := <-errs:
+			var exceeded cost.BudgetExceededError
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("expected a budget error while other calls are in flight, got %v", err)
+			}
+		case <-timeout:
+			t.Fatalf("expected %d calls to be rejected while the others were in flight, got %d", calls-admitted, rejected)
+		}
+	}
+	once.Do(func() { close(release) })
+	for i := 0; i < admitted; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	// The reservations are replaced by the $0.30 prompt cost of each call.
+	spent, err := guard.Spent("team-a", cost.Daily)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !almostEqual(spent, 0.9) {
+		t.Errorf("expected spend of 0.9 after settling, got %v", spent)
+	}
+}
@@ -0,0 +1,135 @@
+// test/metrics/metrics_test.go
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/metrics"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestCollectorPrometheusExport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("anthropic-ratelimit-tokens-remaining", "9000")
+		w.Header().Set("anthropic-ratelimit-tokens-reset", "2024-01-01T00:00:00Z")
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{
+			Usage: models.MessageUsage{PromptTokens: 10, CompletionTokens: 20, CacheReadInputTokens: 3},
+		})
+	}))
+	defer server.Close()
+
+	exporter := metrics.NewPrometheusExporter()
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		metrics.WithMetrics(metrics.NewCollector(exporter)),
+	)
+	client.SetBaseURL(server.URL)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.CreateMessage(context.Background(), &models.MessageRequest{MaxTokens: 10}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+
+	expected := []string{
+		"# TYPE anthropic_requests_total counter",
+		`anthropic_requests_total{endpoint="/v1/messages",model="",status="200"} 2`,
+		`anthropic_tokens_total{model="",type="input"} 20`,
+		`anthropic_tokens_total{model="",type="output"} 40`,
+		`anthropic_tokens_total{model="",type="cache_read"} 6`,
+		"# TYPE anthropic_request_duration_seconds histogram",
+		`anthropic_request_duration_seconds_count{endpoint="/v1/messages",model="",status="200"} 2`,
+		`anthropic_request_duration_seconds_bucket{endpoint="/v1/messages",model="",status="200",le="+Inf"} 2`,
+		"anthropic_ratelimit_tokens_remaining 9000",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in output:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "anthropic_ratelimit_tokens_reset") {
+		t.Error("expected non-numeric rate-limit header to be skipped")
+	}
+}
+
+type recordingBackend struct {
+	counters map[string]float64
+}
+
+func (b *recordingBackend) AddCounter(name string, labels metrics.Labels, value float64) {
+	b.counters[name+"/"+labels["status"]] += value
+}
+
+func (b *recordingBackend) ObserveHistogram(name string, labels metrics.Labels, value float64) {}
+
+func (b *recordingBackend) SetGauge(name string, labels metrics.Labels, value float64) {}
+
+func TestCollectorCountsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message": "rate limited"}`))
+	}))
+	defer server.Close()
+
+	backend := &recordingBackend{counters: make(map[string]float64)}
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		metrics.WithMetrics(metrics.NewCollector(backend)),
+	)
+	client.SetBaseURL(server.URL)
+
+	if _, err := client.CreateMessage(context.Background(), &models.MessageRequest{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := backend.counters[metrics.RequestsTotal+"/429"]; got != 1 {
+		t.Errorf("expected one request with status 429, got %v", got)
+	}
+}
+
+// retryOnce is a retrier that repeats a call once after a server error.
+type retryOnce struct{}
+
+func (retryOnce) Do(fn func() (*http.Response, error)) (*http.Response, error) {
+	resp, err := fn()
+	if err == nil && resp.StatusCode >= 500 {
+		resp.Body.Close()
+		return fn()
+	}
+	return resp, err
+}
+
+func TestCollectorCountsRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls%2 == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{})
+	}))
+	defer server.Close()
+
+	backend := &recordingBackend{counters: make(map[string]float64)}
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		metrics.WithMetrics(metrics.NewCollector(backend)),
+	)
+	client.SetBaseURL(server.URL)
+
+	if _, err := client.CreateMessage(context.Background(), &models.MessageRequest{}, api.WithRequestRetrier(retryOnce{})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := backend.counters[metrics.RetriesTotal+"/200"]; got != 1 {
+		t.Errorf("expected one retry of a per-call retrier, got %v", got)
+	}
+}
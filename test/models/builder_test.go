@@ -0,0 +1,86 @@
+// test/models/builder_test.go
+package models_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	req, err := models.NewRequest("claude-sonnet-4-20250514", 4096).
+		System("You are a careful reviewer.").
+		Tool("lookup", "Looks up a word", map[string]interface{}{"type": "object"}).Cache().
+		User("Review this document").
+		DocumentText("Otters hold hands.").Cache().
+		Image("image/png", []byte{0x89, 'P', 'N', 'G'}).
+		Assistant("").
+		ToolUse("toolu_1", "lookup", map[string]string{"word": "otter"}).
+		ToolResult("toolu_1", "A semiaquatic mammal", false).
+		Text("Continue").
+		Thinking(2048).
+		Temperature(1).
+		TopK(5).
+		StopSequences("END").
+		UserID("user-1").
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.System != "You are a careful reviewer." || req.Thinking.BudgetTokens != 2048 || req.TopK != 5 || req.Metadata.UserID != "user-1" {
+		t.Errorf("unexpected request parameters %+v", req)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].CacheControl == nil || string(req.Tools[0].InputSchema) != `{"type":"object"}` {
+		t.Errorf("unexpected tools %+v", req.Tools)
+	}
+	if len(req.Messages) != 3 {
+		t.Fatalf("expected 3 turns, got %d", len(req.Messages))
+	}
+	user := req.Messages[0].Blocks
+	if len(user) != 3 || user[1].Type != models.DocumentBlock || user[1].CacheControl == nil || user[2].Source.Data != "iVBORw==" {
+		t.Errorf("unexpected user blocks %+v", user)
+	}
+	if blocks := req.Messages[1].Blocks; len(blocks) != 1 || blocks[0].Type != models.ToolUseBlock {
+		t.Errorf("expected the tool call in the assistant turn, got %+v", blocks)
+	}
+	if blocks := req.Messages[2].Blocks; len(blocks) != 2 || blocks[0].Type != models.ToolResultBlock || blocks[1].Text != "Continue" {
+		t.Errorf("expected the tool result and text in one user turn, got %+v", blocks)
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"cache_control":{"type":"ephemeral"}`) {
+		t.Errorf("expected cache control in the encoded request, got %s", data)
+	}
+}
+
+func TestRequestBuilderErrors(t *testing.T) {
+	_, err := models.NewRequest("claude-3-haiku-20240307", 8192).
+		Cache().
+		User("").
+		Tool("broken", "", func() {}).
+		UseTool("missing").
+		Build()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var problems models.ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	if got := fields(err); strings.Join(got, ",") != "max_tokens,tool_choice.name,messages[0].content" {
+		t.Errorf("unexpected validation problems %v", got)
+	}
+	for _, want := range []string{"Cache called before", "tool broken"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
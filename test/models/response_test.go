@@ -0,0 +1,90 @@
+// test/models/response_test.go
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func TestResponseAccessors(t *testing.T) {
+	var resp models.MessageResponse
+	err := json.Unmarshal([]byte(`{
+		"id": "msg_1",
+		"choices": [{
+			"index": 0,
+			"finish_reason": "tool_use",
+			"message": {"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "Check the docs.", "signature": "sig"},
+				{"type": "redacted_thinking", "data": "secret"},
+				{"type": "text", "text": "Otters sleep ", "citations": [
+					{"type": "char_location", "cited_text": "Sea otters hold hands", "document_index": 0, "start_char_index": 0, "end_char_index": 21}
+				]},
+				{"type": "text", "text": "holding hands."},
+				{"type": "tool_use", "id": "toolu_1", "name": "search", "input": {"q": "otters"}}
+			]}
+		}]
+	}`), &resp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := resp.Text(); got != "Otters sleep holding hands." {
+		t.Errorf("unexpected text %q", got)
+	}
+	if got := resp.Thinking(); got != "Check the docs." {
+		t.Errorf("unexpected thinking %q", got)
+	}
+	if uses := resp.ToolUses(); len(uses) != 1 || uses[0].Name != "search" || string(uses[0].Input) != `{"q": "otters"}` {
+		t.Errorf("unexpected tool uses %+v", uses)
+	}
+	if citations := resp.Citations(); len(citations) != 1 || citations[0].CitedText != "Sea otters hold hands" || citations[0].EndCharIndex != 21 {
+		t.Errorf("unexpected citations %+v", citations)
+	}
+	if resp.StopReason() != models.StopToolUse || resp.StopReason().Unfinished() {
+		t.Errorf("unexpected stop reason %q", resp.StopReason())
+	}
+
+	message := resp.ToAssistantMessage()
+	if message.Role != models.AssistantRole || len(message.Blocks) != 5 {
+		t.Fatalf("expected every block in the assistant message, got %+v", message)
+	}
+	message.Blocks[0].Thinking = "changed"
+	if resp.Thinking() != "Check the docs." {
+		t.Error("expected ToAssistantMessage to copy the blocks")
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var roundTrip models.Message
+	if err := json.Unmarshal(data, &roundTrip); err != nil || roundTrip.Blocks[1].Data != "secret" || roundTrip.Blocks[0].Signature != "sig" {
+		t.Errorf("expected thinking blocks to round-trip, got %s (%v)", data, err)
+	}
+}
+
+func TestResponseAccessorsEmpty(t *testing.T) {
+	for name, resp := range map[string]*models.MessageResponse{
+		"nil":        nil,
+		"no choices": {},
+		"plain text": {Choices: []models.MessageChoice{{Message: models.Message{Content: "Hi"}, FinishReason: "max_tokens"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if resp.ToolUses() != nil || resp.Citations() != nil || resp.Thinking() != "" {
+				t.Error("expected no tool uses, citations or thinking")
+			}
+			message := resp.ToAssistantMessage()
+			if message.Role != models.AssistantRole {
+				t.Errorf("expected an assistant message, got %+v", message)
+			}
+			if name == "plain text" {
+				if resp.Text() != "Hi" || message.Content != "Hi" || !resp.StopReason().Unfinished() {
+					t.Errorf("unexpected accessors on %+v", resp)
+				}
+			} else if resp.Text() != "" || resp.StopReason() != models.StopUnknown {
+				t.Errorf("expected empty accessors, got %q, %q", resp.Text(), resp.StopReason())
+			}
+		})
+	}
+}
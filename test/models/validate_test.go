@@ -0,0 +1,123 @@
+// test/models/validate_test.go
+package models_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+)
+
+func fields(err error) []string {
+	var errs models.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *models.MessageRequest {
+		return &models.MessageRequest{
+			Model:     "claude-3-5-haiku-20241022",
+			MaxTokens: 1024,
+			Messages: []models.Message{
+				{Role: models.UserRole, Content: "Weather?"},
+				{Role: models.AssistantRole, Blocks: []models.ContentBlock{{Type: models.ToolUseBlock, ID: "toolu_1", Name: "weather"}}},
+				{Role: models.UserRole, Blocks: []models.ContentBlock{{Type: models.ToolResultBlock, ToolUseID: "toolu_1", Content: "Sunny"}}},
+			},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("expected a valid request, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *models.MessageRequest)
+		fields []string
+	}{
+		{"no messages", func(r *models.MessageRequest) { r.Messages = nil }, []string{"messages"}},
+		{"first turn not user", func(r *models.MessageRequest) { r.Messages = r.Messages[1:] }, []string{"messages[0].role"}},
+		{"system role", func(r *models.MessageRequest) {
+			r.Messages = append([]models.Message{{Role: models.SystemRole, Content: "Be brief"}}, r.Messages...)
+		}, []string{"messages[0].role", "messages[0].role"}},
+		{"empty content", func(r *models.MessageRequest) { r.Messages[0].Content = " " }, []string{"messages[0].content"}},
+		{"unmatched tool result", func(r *models.MessageRequest) { r.Messages[2].Blocks[0].ToolUseID = "toolu_2" }, []string{"messages[2].content[0].tool_use_id"}},
+		{"max tokens over model limit", func(r *models.MessageRequest) { r.MaxTokens = 10000 }, []string{"max_tokens"}},
+		{"thinking budget over max tokens", func(r *models.MessageRequest) {
+			r.Thinking = &models.Thinking{Type: "enabled", BudgetTokens: 2048}
+		}, []string{"thinking.budget_tokens"}},
+		{"image over size", func(r *models.MessageRequest) {
+			r.Messages[0].Blocks = []models.ContentBlock{{Type: models.ImageBlock, Source: &models.Source{
+				Type: models.Base64Source, MediaType: "image/png", Data: strings.Repeat("A", models.MaxImageSize*4/3+8),
+			}}}
+		}, []string{"messages[0].content[0].source.data"}},
+		{"several problems", func(r *models.MessageRequest) {
+			r.MaxTokens = 0
+			r.Temperature = 2
+			r.Messages[0].Content = ""
+		}, []string{"max_tokens", "temperature", "messages[0].content"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(req)
+			err := req.Validate()
+			got := fields(err)
+			if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("expected problems with %v, got %v (%v)", tt.fields, got, err)
+			}
+		})
+	}
+}
+
+func TestMaxOutputTokensFor(t *testing.T) {
+	tests := []struct {
+		model string
+		limit int
+		known bool
+	}{
+		{"claude-3-5-sonnet-20241022", 8192, true},
+		{"claude-3-7-sonnet-latest", 64000, true},
+		{"claude-opus-4-20250514", 32000, true},
+		{"claude-opus-4-1", 32000, true},
+		{"claude-opus-4-5-20251101", 64000, true},
+		{"claude-opus-4-9", 0, false},
+		{"claude-future", 0, false},
+	}
+	for _, tt := range tests {
+		limit, known := models.MaxOutputTokensFor(tt.model)
+		if limit != tt.limit || known != tt.known {
+			t.Errorf("%s: expected (%d, %v), got (%d, %v)", tt.model, tt.limit, tt.known, limit, known)
+		}
+	}
+}
+
+func TestWithValidation(t *testing.T) {
+	server := anthropictest.NewServer()
+	defer server.Close()
+	client := api.NewClient("api-key", api.WithHTTPClient(server.Client()), api.WithValidation())
+	client.SetBaseURL(server.URL)
+
+	req := &models.MessageRequest{Messages: []models.Message{{Role: models.AssistantRole, Content: "Hi"}}}
+	_, err := client.CreateMessage(context.Background(), req)
+	if len(fields(err)) != 2 {
+		t.Errorf("expected two validation errors, got %v", err)
+	}
+	server.AssertRequestCount(t, "/v1/messages", 0)
+
+	req = &models.MessageRequest{Messages: []models.Message{{Role: models.UserRole, Content: "Hi"}}, MaxTokens: 16}
+	if _, err := client.CreateMessage(context.Background(), req); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	server.AssertRequestCount(t, "/v1/messages", 1)
+}
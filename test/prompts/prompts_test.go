@@ -0,0 +1,131 @@
+// test/prompts/prompts_test.go
+package prompts_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/anthropictest"
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/prompts"
+)
+
+type review struct {
+	Language string
+	Code     string
+	Rules    []string
+	Focus    string `prompt:"optional"`
+}
+
+func newReview() *prompts.Template[review] {
+	return prompts.New[review]("review", "v2").
+		Partial("rules", `{{range .}}- {{.}}
+{{end}}`).
+		System(`You review {{.Language}} code. Follow these rules:
+{{template "rules" .Rules}}`).
+		User(`{{.Code | escape | tag "code"}}{{if .Focus}}
+Focus on {{.Focus}}.{{end}}`).
+		Assistant("<review>")
+}
+
+func TestRender(t *testing.T) {
+	tmpl := newReview()
+	if got := strings.Join(tmpl.Variables(), ","); got != "Code,Focus,Language,Rules" {
+		t.Errorf("unexpected variables %s", got)
+	}
+
+	prompt, err := tmpl.Render(review{Language: "Go", Code: "if a < b {}", Rules: []string{"Be kind", "Be brief"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "You review Go code. Follow these rules:\n- Be kind\n- Be brief"; prompt.System != want {
+		t.Errorf("unexpected system prompt %q", prompt.System)
+	}
+	if len(prompt.Messages) != 2 || prompt.Messages[1].Role != models.AssistantRole {
+		t.Fatalf("unexpected messages %+v", prompt.Messages)
+	}
+	if want := "<code>\nif a &lt; b {}\n</code>"; prompt.Messages[0].Content != want {
+		t.Errorf("unexpected user turn %q", prompt.Messages[0].Content)
+	}
+
+	params := models.MessageRequest{Model: "claude-3-5-haiku-20241022", MaxTokens: 512}
+	req := prompt.Request(params)
+	if req.System != prompt.System || len(req.Messages) != 2 || req.MaxTokens != 512 || len(params.Messages) != 0 {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestRenderMissingVariables(t *testing.T) {
+	_, err := newReview().Render(review{Language: "Go"})
+	var missing prompts.MissingVariablesError
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected missing variables, got %v", err)
+	}
+	if got := strings.Join(missing.Variables, ","); got != "Code,Rules" {
+		t.Errorf("unexpected missing variables %s", got)
+	}
+
+	greeting := prompts.New[map[string]string]("greeting", "v1").User("Hello {{.Name}}, {{with .Place}}{{.}}{{end}}")
+	if _, err := greeting.Render(map[string]string{"Name": ""}); !errors.As(err, &missing) || strings.Join(missing.Variables, ",") != "Place" {
+		t.Errorf("expected Place to be missing, got %v", err)
+	}
+	if _, err := greeting.Render(map[string]string{"Name": "", "Place": "Oslo"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRenderParseError(t *testing.T) {
+	_, err := prompts.New[review]("broken", "v1").User("{{.Code").Render(review{})
+	if err == nil || !strings.Contains(err.Error(), "prompt broken") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestPromptOptions(t *testing.T) {
+	var logs bytes.Buffer
+	fake := anthropictest.NewServer()
+	defer fake.Close()
+	fake.EnqueueText("Looks good.")
+	client := api.NewClient("api-key", api.WithHTTPClient(fake.Client()), api.WithSlogLogger(
+		slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})), api.DefaultRedactionPolicy))
+	client.SetBaseURL(fake.URL)
+
+	prompt, err := newReview().Render(review{Language: "Go", Code: "x := 1", Rules: []string{"Be kind"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := prompt.Request(models.MessageRequest{Model: "claude-3-5-haiku-20241022", MaxTokens: 512})
+	if _, err := client.CreateMessage(context.Background(), req, prompt.Options()...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(logs.String(), "prompt=review prompt_version=v2") {
+		t.Errorf("expected the prompt in the logs, got %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "Making POST") {
+		t.Errorf("expected a request log, got %s", logs.String())
+	}
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "Making POST") && !strings.Contains(line, "prompt=review prompt_version=v2") {
+			t.Errorf("expected the prompt in request logs, got %s", line)
+		}
+	}
+
+	logs.Reset()
+	fake.EnqueueError(anthropictest.InvalidRequestError("bad request"))
+	if _, err := client.CreateMessage(context.Background(), req, prompt.Options()...); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "level=ERROR") && !strings.Contains(line, "prompt=review prompt_version=v2") {
+			t.Errorf("expected the prompt in error logs, got %s", line)
+		}
+	}
+	if !strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("expected an error log, got %s", logs.String())
+	}
+}
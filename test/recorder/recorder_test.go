@@ -0,0 +1,198 @@
+// test/recorder/recorder_test.go
+package recorder_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/recorder"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{ID: "msg-recorded"})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "create_message.json")
+	newRequest := func() *models.MessageRequest {
+		return &models.MessageRequest{
+			Messages: []models.Message{{Role: models.UserRole, Content: "Hi"}},
+			Metadata: &models.Metadata{UserID: "user-123"},
+		}
+	}
+
+	rec, err := recorder.New(path, recorder.Options{Mode: recorder.ModeReplayOrRecord, ScrubFields: []string{"user_id"}})
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	if !rec.Recording() {
+		t.Fatal("expected recorder to record without a cassette")
+	}
+	client := api.NewClient("sk-ant-secret", api.WithHTTPClient(rec.HTTPClient()))
+	client.SetBaseURL(server.URL)
+	if _, err := client.CreateMessage(context.Background(), newRequest()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("failed to save cassette: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %v", err)
+	}
+	for _, secret := range []string{"sk-ant-secret", "user-123"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("expected %q to be scrubbed from cassette", secret)
+		}
+	}
+
+	rec, err = recorder.New(path, recorder.Options{Mode: recorder.ModeReplayOrRecord, ScrubFields: []string{"user_id"}})
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	if rec.Recording() {
+		t.Fatal("expected recorder to replay the existing cassette")
+	}
+	client = api.NewClient("sk-ant-other", api.WithHTTPClient(rec.HTTPClient()))
+	client.SetBaseURL(server.URL)
+	resp, err := client.CreateMessage(context.Background(), newRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ID != "msg-recorded" || requests != 1 {
+		t.Errorf("expected replayed response without a request, got %q after %d requests", resp.ID, requests)
+	}
+
+	changed := newRequest()
+	changed.MaxTokens = 99
+	if _, err := client.CreateMessage(context.Background(), changed); err == nil {
+		t.Error("expected strict matching to reject a different body")
+	}
+}
+
+func TestReplayStreamChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stream.json")
+	cassette := &recorder.Cassette{Interactions: []*recorder.Interaction{{
+		Request: recorder.RecordedRequest{Method: http.MethodPost, URL: "http://example.invalid/v1/messages"},
+		Response: recorder.RecordedResponse{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/event-stream"}},
+			Chunks: []recorder.Chunk{
+				{Data: "data: {\"id\": \"a\"}\n"},
+				{Data: "data: {\"id\": \"b\"}\n"},
+			},
+		},
+	}}}
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("failed to save cassette: %v", err)
+	}
+
+	rec, err := recorder.New(path, recorder.Options{Mode: recorder.ModeReplay, Lenient: true})
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	client := api.NewClient("api-key", api.WithHTTPClient(rec.HTTPClient()))
+	client.SetBaseURL("http://example.invalid")
+
+	stream, errs := client.StreamMessages(context.Background(), &models.MessageRequest{})
+	var ids []string
+	for chunk := range stream {
+		ids = append(ids, chunk.ID)
+	}
+	for err := range errs {
+		t.Errorf("unexpected stream error: %v", err)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Errorf("expected chunks a,b, got %v", ids)
+	}
+}
+
+type oneByteTransport struct{ body string }
+
+func (t oneByteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(iotest.OneByteReader(strings.NewReader(t.body))),
+		Request:    r,
+	}, nil
+}
+
+func TestRecordSplitRunes(t *testing.T) {
+	body := "{\"id\": \"café ☕\"}\xff"
+	path := filepath.Join(t.TempDir(), "runes.json")
+	rec, err := recorder.New(path, recorder.Options{Mode: recorder.ModeRecord, Transport: oneByteTransport{body}})
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	resp, err := rec.HTTPClient().Post("http://example.invalid/v1/messages", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("failed to save cassette: %v", err)
+	}
+
+	cassette, err := recorder.LoadCassette(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	recorded := cassette.Interactions[0].Response
+	if recorded.Body() != body {
+		t.Errorf("expected the body to survive the cassette, got %q", recorded.Body())
+	}
+	for _, chunk := range recorded.Chunks {
+		if strings.ContainsRune(chunk.Data, utf8.RuneError) {
+			t.Errorf("expected chunks to hold whole runes, got %q", chunk.Data)
+		}
+	}
+
+	rec, err = recorder.New(path, recorder.Options{Mode: recorder.ModeReplay, Lenient: true})
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	resp, err = rec.HTTPClient().Post("http://example.invalid/v1/messages", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	if string(replayed) != body {
+		t.Errorf("expected the replayed body %q, got %q", body, replayed)
+	}
+}
+
+func TestLenientMatcherComparesPaths(t *testing.T) {
+	recorded := &recorder.RecordedRequest{Method: http.MethodPost, URL: "https://api.anthropic.com/v1/messages?beta=true"}
+	cases := []struct {
+		url  string
+		want bool
+	}{
+		{"http://127.0.0.1:8080/v1/messages", true},
+		{"http://127.0.0.1:8080/v1/messages?beta=false", true},
+		{"http://127.0.0.1:8080/messages", false},
+		{"http://127.0.0.1:8080/v1/messages/batches", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.url, nil)
+		if got := recorder.LenientMatcher(req, nil, recorded); got != c.want {
+			t.Errorf("LenientMatcher(%s) = %v, want %v", c.url, got, c.want)
+		}
+	}
+}
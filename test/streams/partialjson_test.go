@@ -0,0 +1,98 @@
+// test/streams/partialjson_test.go
+package streams_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/streams"
+)
+
+func TestCompletePartialJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "empty", input: "", expected: ""},
+		{name: "open object", input: "{", expected: "{}"},
+		{name: "partial key", input: `{"loc`, expected: "{}"},
+		{name: "key without value", input: `{"location":`, expected: "{}"},
+		{name: "partial string value", input: `{"location": "San Fra`, expected: `{"location":"San Fra"}`},
+		{name: "trailing comma", input: `{"a": 1,`, expected: `{"a":1}`},
+		{name: "nested array", input: `{"a": [1, 2, {"b": tr`, expected: `{"a":[1,2,{"b":true}]}`},
+		{name: "partial number", input: `[1.5, -`, expected: `[1.5]`},
+		{name: "partial exponent", input: `[12e`, expected: `[12]`},
+		{name: "partial escape", input: `["a\`, expected: `["a"]`},
+		{name: "partial unicode escape", input: `["a\u00`, expected: `["a"]`},
+		{name: "escaped quote", input: `["a\"b`, expected: `["a\"b"]`},
+		{name: "complete", input: `{"a": null, "b": [] }`, expected: `{"a":null,"b":[]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := streams.CompletePartialJSON([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, string(got))
+			}
+		})
+	}
+}
+
+func TestCompletePartialJSONInvalid(t *testing.T) {
+	for _, input := range []string{`{]`, `{"a" 1}`, `[1] 2`, `[trux]`} {
+		if _, err := streams.CompletePartialJSON([]byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestUnmarshalPartialJSON(t *testing.T) {
+	var weather struct {
+		Location string   `json:"location"`
+		Units    string   `json:"units"`
+		Days     []string `json:"days"`
+	}
+	if err := streams.UnmarshalPartialJSON([]byte(`{"location": "Paris", "days": ["mon", "tu`), &weather); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if weather.Location != "Paris" || weather.Units != "" {
+		t.Errorf("unexpected value: %+v", weather)
+	}
+	if !reflect.DeepEqual(weather.Days, []string{"mon", "tu"}) {
+		t.Errorf("expected days [mon tu], got %v", weather.Days)
+	}
+}
+
+func TestToolInputAccumulator(t *testing.T) {
+	acc := streams.NewToolInputAccumulator()
+	events := []models.StreamEvent{
+		{Type: models.ContentBlockStartEvent, Index: 1, ContentBlock: &models.ContentBlock{Type: "tool_use", Name: "get_weather"}},
+		{Type: models.ContentBlockDeltaEvent, Index: 1, Delta: &models.StreamDelta{Type: models.InputJSONDelta, PartialJSON: `{"location":`}},
+		{Type: models.ContentBlockDeltaEvent, Index: 1, Delta: &models.StreamDelta{Type: models.InputJSONDelta, PartialJSON: ` "Lon`}},
+		{Type: models.ContentBlockDeltaEvent, Index: 0, Delta: &models.StreamDelta{Type: models.TextDelta, Text: "ignored"}},
+	}
+	for _, event := range events {
+		acc.Add(event)
+	}
+
+	input := acc.Input(1)
+	if input == nil {
+		t.Fatal("expected input for block 1")
+	}
+	value, err := input.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{"location": "Lon"}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("expected %v, got %v", expected, value)
+	}
+	if acc.Input(0) != nil {
+		t.Error("expected no input for text block")
+	}
+}
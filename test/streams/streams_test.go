@@ -4,13 +4,12 @@ package streams_test
 import (
     "bytes"
     "encoding/json"
-    "errors"
     "io"
     "strings"
     "testing"
 
-    "github.com/Aanthord/anthropic-go/pkg/models"
-    "github.com/Aanthord/anthropic-go/pkg/streams"
+    "github.com/Aanthord/go-anthropic/pkg/models"
+    "github.com/Aanthord/go-anthropic/pkg/streams"
 )
 
 func TestConsumeStream(t *testing.T) {
@@ -158,7 +157,7 @@ func TestCompletionStreamConverter(t *testing.T) {
         t.Fatalf("expected %d completions, got %d", len(expected), len(received))
     }
 
-    for _, err := range errors {
+    for err := range errors {
         t.Errorf("unexpected error: %v", err)
     }
 
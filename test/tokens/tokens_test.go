@@ -0,0 +1,172 @@
+// test/tokens/tokens_test.go
+package tokens_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/tokens"
+)
+
+func pngOf(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestMeasure(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n1 0 obj << /Type /Pages /Count 2 >>\n2 0 obj << /Type /Page >>\n3 0 obj << /Type/Page >>\n")
+	req, err := models.NewRequest("claude-3-5-haiku-20241022", 1024).
+		System("Be brief.").
+		Tool("lookup", "Looks up a word", map[string]string{"type": "object"}).
+		User("Compare these").
+		Image("image/png", pngOf(t, 3136, 1000)).
+		ImageURL("https://example.com/cat.png").
+		Document("application/pdf", pdf).
+		DocumentText("Otters.").
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f := tokens.Measure(req)
+	want := tokens.Features{
+		Chars:       len("Be brief.") + len("Compare these") + len("Otters."),
+		Pixels:      tokens.ScaledPixels(3136, 1000) + tokens.MaxImagePixels,
+		Pages:       2,
+		SchemaChars: len("lookup") + len("Looks up a word") + len(`{"type":"object"}`),
+		Tools:       1,
+		Messages:    1,
+	}
+	if f != want {
+		t.Errorf("expected %+v, got %+v", want, f)
+	}
+	if got := tokens.ScaledPixels(3136, 1000); got != 1568*500 {
+		t.Errorf("expected the long edge to be scaled to 1568, got %d pixels", got)
+	}
+
+	estimate := tokens.DefaultEstimator.Estimate(req)
+	if estimate < 6000 || estimate > 8000 {
+		t.Errorf("unexpected estimate %d", estimate)
+	}
+}
+
+func TestMeasurePDFPages(t *testing.T) {
+	cases := []struct {
+		name    string
+		pdf     string
+		url     bool
+		pages   int
+		unknown int
+	}{
+		{
+			name:  "page tree root count",
+			pdf:   "%PDF-1.5\n1 0 obj << /Type /Pages /Kids [2 0 R 3 0 R] /Count 12 >>\n2 0 obj << /Parent 1 0 R /Count 5 /Type /Pages >>\n4 0 obj << /Type /ObjStm /N 9 /Filter /FlateDecode >>\n",
+			pages: 12,
+		},
+		{
+			name:  "page objects",
+			pdf:   "%PDF-1.4\n2 0 obj << /Type /Page >>\n3 0 obj << /Type /Page >>\n4 0 obj << /Type /Page >>\n",
+			pages: 3,
+		},
+		{
+			name:    "compressed page tree",
+			pdf:     "%PDF-1.5\n4 0 obj << /Type /ObjStm /N 9 /Filter /FlateDecode >>\nstream\nx\x9c\nendstream\n",
+			pages:   1,
+			unknown: 1,
+		},
+		{name: "url", url: true, pages: 1, unknown: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := models.NewRequest("claude-3-5-haiku-20241022", 1024).User("Summarize")
+			if c.url {
+				b.DocumentURL("https://example.com/report.pdf")
+			} else {
+				b.Document("application/pdf", []byte(c.pdf))
+			}
+			req, err := b.Build()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			f := tokens.Measure(req)
+			if f.Pages != c.pages || f.UnknownPages != c.unknown {
+				t.Errorf("expected %d pages with %d unknown, got %d with %d unknown", c.pages, c.unknown, f.Pages, f.UnknownPages)
+			}
+		})
+	}
+}
+
+func TestParseSample(t *testing.T) {
+	request := []byte(`{"model":"claude-3-5-haiku-20241022","messages":[{"role":"user","content":"Hello there"}]}`)
+	tests := []struct {
+		name     string
+		response string
+		tokens   int
+	}{
+		{"message", `{"id":"msg_1","usage":{"input_tokens":12,"cache_read_input_tokens":30,"output_tokens":5}}`, 42},
+		{"models usage", `{"id":"msg_1","usage":{"prompt_tokens":12}}`, 12},
+		{"stream", "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":7}}}\n\nevent: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":3}}\n\n", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := tokens.ParseSample(request, []byte(tt.response))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sample.InputTokens != tt.tokens || sample.Features.Chars != len("Hello there") {
+				t.Errorf("unexpected sample %+v", sample)
+			}
+		})
+	}
+
+	if _, err := tokens.ParseSample(request, []byte(`{"type":"error"}`)); !errors.Is(err, tokens.ErrNoUsage) {
+		t.Errorf("expected ErrNoUsage, got %v", err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	truth := tokens.Factors{Base: 10, PerChar: 0.3, PerPixel: 1.0 / 800, PerPage: 1500, PerSchema: 0.4, PerTools: 300, PerMessage: 3}
+	var samples []tokens.Sample
+	for i := 0; i < 40; i++ {
+		f := tokens.Features{
+			Chars:    200 + 137*i,
+			Pixels:   (i % 3) * 400000,
+			Pages:    i % 4,
+			Messages: 1 + i%5,
+		}
+		if i%2 == 0 {
+			f.Tools = 1 + i%3
+			f.SchemaChars = 100 + 31*i
+		}
+		samples = append(samples, tokens.Sample{Features: f, InputTokens: int(truth.Apply(f))})
+	}
+
+	fitted, err := tokens.Fit(samples, tokens.DefaultFactors)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before, after := tokens.MeanError(samples, tokens.DefaultFactors), tokens.MeanError(samples, fitted)
+	if after > 0.02 || after >= before {
+		t.Errorf("expected calibration to fit the samples, error %.3f before and %.3f after", before, after)
+	}
+
+	// Features no sample exercises keep their prior factor.
+	for i := range samples {
+		samples[i].Features.Pages = 0
+	}
+	if fitted, err = tokens.Fit(samples, tokens.DefaultFactors); err != nil || fitted.PerPage != tokens.DefaultFactors.PerPage {
+		t.Errorf("expected the prior page factor, got %+v (%v)", fitted, err)
+	}
+
+	if _, err := tokens.Fit(nil, tokens.DefaultFactors); err == nil || !strings.Contains(err.Error(), "no samples") {
+		t.Errorf("expected an error without samples, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// test/tracing/tracing_test.go
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/Aanthord/go-anthropic/pkg/api"
+	"github.com/Aanthord/go-anthropic/pkg/models"
+	"github.com/Aanthord/go-anthropic/pkg/tracing"
+)
+
+func TestCreateMessageSpans(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("request-id", "req_123")
+		_ = json.NewEncoder(w).Encode(models.MessageResponse{
+			ID:      "msg_1",
+			Model:   "claude-3-haiku",
+			Choices: []models.MessageChoice{{FinishReason: "end_turn"}},
+			Usage:   models.MessageUsage{PromptTokens: 12, CompletionTokens: 34, CacheReadInputTokens: 5},
+		})
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		tracing.WithTracing(tracing.WithTracerProvider(provider)),
+	)
+	client.SetBaseURL(server.URL)
+
+	_, err := client.CreateMessage(context.Background(), &models.MessageRequest{MaxTokens: 256}, api.WithLabel("prompt", "review"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	attempt, call := spans[0], spans[1]
+	if call.Name != "chat" {
+		t.Errorf("expected span name 'chat', got %q", call.Name)
+	}
+	if attempt.Parent.SpanID() != call.SpanContext.SpanID() {
+		t.Error("expected attempt span to be a child of the call span")
+	}
+
+	attrs := attribute.NewSet(call.Attributes...)
+	expected := map[attribute.Key]attribute.Value{
+		tracing.SystemKey:                 attribute.StringValue("anthropic"),
+		tracing.RequestMaxTokensKey:       attribute.IntValue(256),
+		tracing.ResponseIDKey:             attribute.StringValue("msg_1"),
+		tracing.UsageInputTokensKey:       attribute.IntValue(12),
+		tracing.UsageOutputTokensKey:      attribute.IntValue(34),
+		tracing.UsageCacheReadTokensKey:   attribute.IntValue(5),
+		tracing.RequestIDKey:              attribute.StringValue("req_123"),
+		tracing.LabelKeyPrefix + "prompt": attribute.StringValue("review"),
+	}
+	for key, value := range expected {
+		got, ok := attrs.Value(key)
+		if !ok || got != value {
+			t.Errorf("expected %s=%v, got %v", key, value.Emit(), got.Emit())
+		}
+	}
+	if got, _ := attrs.Value(tracing.ResponseFinishReasonsKey); len(got.AsStringSlice()) != 1 || got.AsStringSlice()[0] != "end_turn" {
+		t.Errorf("expected finish reasons [end_turn], got %v", got.Emit())
+	}
+}
+
+func TestToolSpanPropagatesContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "agent")
+	_, span := tracing.StartToolSpan(ctx, "get_weather", "toolu_1", tracing.WithTracerProvider(provider))
+	span.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Parent.TraceID() != parent.SpanContext().TraceID() {
+		t.Error("expected tool span in the parent trace")
+	}
+}
+
+func TestStreamErrorSpan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"id\":\"msg_1\"}\n\ndata: {broken\n\n"))
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := api.NewClient("api-key",
+		api.WithHTTPClient(server.Client()),
+		tracing.WithTracing(tracing.WithTracerProvider(provider)),
+	)
+	client.SetBaseURL(server.URL)
+
+	stream, errs := client.StreamMessages(context.Background(), &models.MessageRequest{MaxTokens: 16})
+	received := 0
+	for stream != nil || errs != nil {
+		select {
+		case _, ok := <-stream:
+			if !ok {
+				stream = nil
+			}
+		case _, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			received++
+		}
+	}
+	if received != 1 {
+		t.Fatalf("expected 1 stream error, got %d", received)
+	}
+
+	spans := exporter.GetSpans()
+	call := spans[len(spans)-1]
+	if call.Status.Code != codes.Error {
+		t.Errorf("expected error status, got %v", call.Status)
+	}
+	if len(call.Events) == 0 || call.Events[len(call.Events)-1].Name != "exception" {
+		t.Errorf("expected the stream error to be recorded, got %v", call.Events)
+	}
+}